@@ -0,0 +1,258 @@
+package creech
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// An Option sets up a Game made by New. Options apply in order.
+type Option func(g *Game) error
+
+// New makes a game from the default config changed by opts, ready to
+// Run or Step. Without WithRenderer it draws nothing.
+func New(opts ...Option) (*Game, error) {
+	g := NewGame(render.NewNull(), time.Second, 0)
+	for _, opt := range opts {
+		err := opt(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := g.Init()
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func WithConfig(cfg Config) Option {
+	return func(g *Game) error {
+		return g.SetConfig(cfg)
+	}
+}
+
+// WithScenario also uses the scenario's seed, a later WithSeed
+// replaces it
+func WithScenario(sc *Scenario) Option {
+	return func(g *Game) error {
+		g.setSeed(sc.Seed)
+		return g.SetScenario(sc)
+	}
+}
+
+func WithSeed(seed int64) Option {
+	return func(g *Game) error {
+		g.setSeed(seed)
+		return nil
+	}
+}
+
+func WithRenderer(r render.Renderer) Option {
+	return func(g *Game) error {
+		g.renderer = r
+		return nil
+	}
+}
+
+// WithTickDuration sets the time between ticks for Run, zero ticks as
+// fast as possible
+func WithTickDuration(d time.Duration) Option {
+	return func(g *Game) error {
+		if d < 0 {
+			return errors.New("tick duration can't be negative")
+		}
+		g.tickDur = d
+		return nil
+	}
+}
+
+// WithFrameRate draws at fps while Run ticks at its own rate, see
+// SetFrameRate
+func WithFrameRate(fps float64) Option {
+	return func(g *Game) error {
+		return g.SetFrameRate(fps)
+	}
+}
+
+// WithMind gives every creech of the species m as its brain
+func WithMind(species string, m Mind) Option {
+	return func(g *Game) error {
+		if g.minds == nil {
+			g.minds = make(map[string]Mind)
+		}
+		g.minds[species] = m
+		return nil
+	}
+}
+
+// WithFoodSpawner replaces the config's food spawning
+func WithFoodSpawner(fs FoodSpawner) Option {
+	return func(g *Game) error {
+		g.foodSpawner = fs
+		return nil
+	}
+}
+
+// WithSystem runs sys each tick after the built in systems
+func WithSystem(sys System) Option {
+	return func(g *Game) error {
+		g.AddSystem(sys)
+		return nil
+	}
+}
+
+func WithMetrics(m MetricsWriter) Option {
+	return func(g *Game) error {
+		g.SetMetrics(m)
+		return nil
+	}
+}
+
+func (g *Game) setSeed(seed int64) {
+	g.seed = seed
+	g.state.rand = rand.New(rand.NewSource(seed))
+}
+
+// A Mind chooses what a creech does each tick, in place of the built
+// in brain which heads for food and flees other creeches. A nil plan
+// does nothing.
+type Mind interface {
+	Plan(g *Game, c *Creech) *Plan
+}
+
+type MindFunc func(g *Game, c *Creech) *Plan
+
+func (f MindFunc) Plan(g *Game, c *Creech) *Plan {
+	return f(g, c)
+}
+
+// A FoodSpawner adds food to the world, once per tick
+type FoodSpawner interface {
+	SpawnFood(g *Game)
+}
+
+type FoodSpawnerFunc func(g *Game)
+
+func (f FoodSpawnerFunc) SpawnFood(g *Game) {
+	f(g)
+}
+
+// Spawns as the config's Food section says
+type configFoodSpawner struct{}
+
+func (configFoodSpawner) SpawnFood(g *Game) {
+	g.state.spawnFood(g.config.Food, g.worldSize, g.ticks)
+}
+
+// Step runs one tick, as Run does on each tick of its clock, and draws
+// it. It returns render.ErrFinished once a scenario has ended.
+func (g *Game) Step() error {
+	return g.tick()
+}
+
+// OnStep calls f with a snapshot after each tick, until unsubscribe
+// is called
+func (g *Game) OnStep(f func(Snapshot)) (unsubscribe func()) {
+	return Subscribe(&g.events, func(StepEvent) {
+		f(g.Snapshot())
+	})
+}
+
+func (g *Game) Tick() int {
+	return g.ticks
+}
+
+func (g *Game) WorldSize() Pos {
+	return g.worldSize
+}
+
+// Config is in use now, which scenario events may have changed
+func (g *Game) Config() Config {
+	return g.config
+}
+
+// Snapshot copies the entities as they are now
+func (g *Game) Snapshot() Snapshot {
+	return g.state.Snapshot(g.ticks)
+}
+
+// Registry holds the entities. Change it only from a Mind, a System
+// or a FoodSpawner, or between calls to Step.
+func (g *Game) Registry() *Registry {
+	return &g.state.entities
+}
+
+func (g *Game) Terrain() *Terrain {
+	return g.state.terrain
+}
+
+// Rand is the source of all randomness in the simulation. Minds and
+// spawners should use it, so a seed repeats a run.
+func (g *Game) Rand() *rand.Rand {
+	return g.state.rand
+}
+
+// AddEntity puts e into the world, e must embed BaseEntity
+func (g *Game) AddEntity(e Entity) (int64, error) {
+	return g.state.entities.Add(e)
+}
+
+// RandomEmptyPos finds somewhere with room for something of this size
+func (g *Game) RandomEmptyPos(size float64) (Pos, error) {
+	return g.state.randomEmptyPos(g.worldSize, size)
+}
+
+// Sees is what c can see now
+func (g *Game) Sees(c *Creech) []Entity {
+	return g.Observe(c.Pos(), c.ViewRegion(), c.ID())
+}
+
+func NewFoodAt(value float64, p Pos) *Food {
+	f := NewFood(value)
+	f.pos = p
+	return f
+}
+
+func (f *Food) Value() float64 {
+	return f.value
+}
+
+func (c *Creech) Name() string {
+	return c.name
+}
+
+func (c *Creech) Params() Params {
+	return c.params
+}
+
+func (c *Creech) Facing() Polar {
+	return c.facing
+}
+
+// Turn by up to the creech's MaxTurn either way
+func (c *Creech) Turn(dTheta float64) {
+	max := c.maxTurn()
+	if dTheta > max {
+		dTheta = max
+	} else if dTheta < -max {
+		dTheta = -max
+	}
+	c.facing = c.facing.Turn(dTheta)
+}
+
+// Forward moves up to the creech's MaxMove, as the terrain allows
+func (c *Creech) Forward(g *Game, d float64) {
+	if d > c.maxMove() {
+		d = c.maxMove()
+	}
+	if d < 0 {
+		d = 0
+	}
+	c.MoveForward(g.state.terrain, d)
+}
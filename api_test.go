@@ -0,0 +1,129 @@
+package creech
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jbert/creech/render"
+
+	. "github.com/jbert/creech/pos"
+)
+
+func TestNewAndStep(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Food.Count = 0
+	cfg.Creeches = []PopulationConfig{
+		{Name: "runner", Species: "runner", Count: 1, Pos: []Pos{{X: 0, Y: 0}}},
+		{Name: "bob", Count: 1, Pos: []Pos{{X: 10, Y: 10}}},
+	}
+
+	runs := 0
+	straight := MindFunc(func(g *Game, c *Creech) *Plan {
+		runs++
+		return NewPlan("STRAIGHT", func() { c.Forward(g, 100) })
+	})
+	spawned := 0
+	spawner := FoodSpawnerFunc(func(g *Game) {
+		if g.Tick()%2 == 0 {
+			_, err := g.AddEntity(NewFoodAt(1, Pos{X: -10, Y: -10}))
+			if err != nil {
+				t.Fatalf("AddEntity: %s", err)
+			}
+			spawned++
+		}
+	})
+	var snaps []Snapshot
+
+	g, err := New(
+		WithConfig(cfg),
+		WithSeed(3),
+		WithMind("runner", straight),
+		WithFoodSpawner(spawner),
+	)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	g.OnStep(func(s Snapshot) { snaps = append(snaps, s) })
+
+	for i := 0; i < 4; i++ {
+		err = g.Step()
+		if err != nil {
+			t.Fatalf("Step: %s", err)
+		}
+	}
+	if g.Tick() != 4 || len(snaps) != 4 || snaps[3].Tick != 4 {
+		t.Fatalf("got tick %d and %d snapshots", g.Tick(), len(snaps))
+	}
+	if runs != 4 {
+		t.Fatalf("mind ran %d times expected 4", runs)
+	}
+	runner := snaps[3].Creeches[0]
+	// Facing north, MaxMove each tick
+	if runner.Plan != "STRAIGHT" || !runner.Pos.Equals(Pos{X: 0, Y: 4 * DefaultParams().MaxMove}) {
+		t.Fatalf("got runner %+v", runner)
+	}
+	if spawned != 2 || len(g.Snapshot().Food) != 2 {
+		t.Fatalf("spawned %d, have %d food", spawned, len(g.Snapshot().Food))
+	}
+
+	// Snapshots are copies
+	snap := g.Snapshot()
+	snap.Creeches[0].Food = -1
+	c, _ := Lookup[*Creech](g.Registry(), snap.Creeches[0].ID)
+	if c.Energy() < 0 {
+		t.Fatalf("changing a snapshot changed the game")
+	}
+}
+
+func TestNewScenario(t *testing.T) {
+	sc := &Scenario{Seed: 5, Config: DefaultConfig(), MaxTicks: 3}
+	g, err := New(WithScenario(sc))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	if g.seed != 5 {
+		t.Fatalf("got seed %d expected the scenario's", g.seed)
+	}
+	for i := 0; i < 2; i++ {
+		err = g.Step()
+		if err != nil {
+			t.Fatalf("Step: %s", err)
+		}
+	}
+	err = g.Step()
+	if !errors.Is(err, render.ErrFinished) {
+		t.Fatalf("got %v after MaxTicks expected ErrFinished", err)
+	}
+
+	_, err = New(WithTickDuration(-time.Second))
+	if err == nil {
+		t.Fatalf("expected an error for a negative tick")
+	}
+	_, err = New(WithScenario(sc), WithSeed(9))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	g, err := New(WithTickDuration(time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.OnStep(func(s Snapshot) {
+		if s.Tick == 3 {
+			cancel()
+		}
+	})
+	err = g.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Got %v expected %v", err, context.Canceled)
+	}
+	// A tick may already be due as the context is cancelled
+	if g.Tick() < 3 {
+		t.Fatalf("Got %v expected at least 3 ticks", g.Tick())
+	}
+}
@@ -0,0 +1,181 @@
+package creech
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// The simulation and the renderer can run on separate clocks. Run
+// ticks every tickDur, or as fast as it can if that is zero. With a
+// frame rate set, a sampler draws the latest state at that rate on its
+// own goroutine, so a slow renderer doesn't hold up the simulation and
+// a fast simulation doesn't flood the renderer.
+
+// SetFrameRate draws at fps while Run ticks at its own rate. Zero, the
+// default, draws every tick as part of the tick.
+func (g *Game) SetFrameRate(fps float64) error {
+	if fps < 0 {
+		return errors.New("frame rate can't be negative")
+	}
+	if fps == 0 {
+		g.frameDur = 0
+		return nil
+	}
+	g.frameDur = time.Duration(float64(time.Second) / fps)
+	return nil
+}
+
+// A frame is an immutable copy of all the renderer needs, so it can
+// be drawn while the simulation carries on
+type frame struct {
+	size       Pos
+	terrain    *Terrain // Replaced rather than changed, so shared
+	drawables  []render.Drawable
+	background []render.DrawCommand
+	inspection *render.Inspection // nil unless the renderer is an Inspector
+}
+
+func (g *Game) frame() frame {
+	rs := g.state.renderables()
+	f := frame{
+		size:       g.worldSize,
+		terrain:    g.state.terrain,
+		drawables:  make([]render.Drawable, len(rs)),
+		background: g.background(),
+	}
+	for i, e := range rs {
+		f.drawables[i] = copyDrawable(e)
+	}
+	if _, ok := g.renderer.(render.Inspector); ok {
+		i := g.inspect()
+		f.inspection = &i
+	}
+	return f
+}
+
+func (f *frame) draw(r render.Renderer) error {
+	err := drawFrame(r, f.background, f.terrain, f.drawables)
+	if err != nil {
+		return fmt.Errorf("Can't Draw: %w", err)
+	}
+	if f.inspection != nil {
+		err = r.(render.Inspector).Inspect(*f.inspection)
+		if err != nil {
+			return fmt.Errorf("Can't Inspect: %w", err)
+		}
+	}
+	return nil
+}
+
+// A Renderable as it was drawn when the frame was taken, so every kind
+// of entity is drawn without the frame knowing how to copy it
+type drawnEntity struct {
+	id   int64
+	i, j int
+	b    byte
+	cmds []render.DrawCommand
+}
+
+// Also tells the terminal about it
+type describedEntity struct {
+	drawnEntity
+	desc render.Description
+}
+
+func copyDrawable(e Renderable) render.Drawable {
+	d := drawnEntity{id: e.ID()}
+	d.i, d.j, d.b = e.Screen()
+	for _, cmd := range e.Web() {
+		// Points may belong to the entity
+		cmd.Points = append([]Pos(nil), cmd.Points...)
+		cmd.Info = append([]render.InfoItem(nil), cmd.Info...)
+		d.cmds = append(d.cmds, cmd)
+	}
+	if desc, ok := e.(render.Describer); ok {
+		return &describedEntity{d, desc.Describe()}
+	}
+	return &d
+}
+
+func (d *drawnEntity) ID() int64                 { return d.id }
+func (d *drawnEntity) Screen() (int, int, byte)  { return d.i, d.j, d.b }
+func (d *drawnEntity) Web() []render.DrawCommand { return d.cmds }
+
+func (d *describedEntity) Describe() render.Description {
+	return d.desc
+}
+
+// Draws frames on its own goroutine, the only one calling the renderer
+// while it runs
+type frameSampler struct {
+	frames chan frame // Holds at most the latest frame
+	errs   chan error
+	done   chan struct{}
+	drawn  Pos // World size last drawn, read once done
+}
+
+func startSampler(r render.Renderer, drawn Pos) *frameSampler {
+	s := &frameSampler{
+		frames: make(chan frame, 1),
+		errs:   make(chan error, 1),
+		done:   make(chan struct{}),
+		drawn:  drawn,
+	}
+	go func() {
+		defer close(s.done)
+		for f := range s.frames {
+			err := resize(r, &s.drawn, f.size)
+			if err == nil {
+				err = f.draw(r)
+			}
+			if err != nil {
+				s.errs <- err
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Replace any frame not yet drawn, never waiting for the renderer.
+// Only the simulation sends, so there is room once it is emptied.
+func (s *frameSampler) offer(f frame) {
+	select {
+	case <-s.frames:
+	default:
+	}
+	s.frames <- f
+}
+
+// Stop once the last frame offered is drawn
+func (s *frameSampler) stop() {
+	close(s.frames)
+	<-s.done
+}
+
+// Tell the renderer the world size if it changed since it last drew.
+// Init checks it is a Resizer if the size can change.
+func resize(r render.Renderer, drawn *Pos, size Pos) error {
+	if *drawn == size {
+		return nil
+	}
+	*drawn = size
+	err := r.(render.Resizer).Resize(size.X, size.Y)
+	if err != nil {
+		return fmt.Errorf("Can't Resize: %w", err)
+	}
+	return nil
+}
+
+// Ready at once, for ticking as fast as possible
+var alwaysReady = func() <-chan time.Time {
+	ch := make(chan time.Time)
+	close(ch)
+	return ch
+}()
@@ -0,0 +1,125 @@
+package creech
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// Counts finished frames, taking a while over each
+type slowRenderer struct {
+	render.Null
+	delay  time.Duration
+	frames int64
+}
+
+func (r *slowRenderer) FinishFrame() error {
+	time.Sleep(r.delay)
+	atomic.AddInt64(&r.frames, 1)
+	return nil
+}
+
+func TestFrameRate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		fps       float64
+		minTicks  int
+		maxFrames int64
+	}{
+		// Drawing holds up every tick
+		{"every tick", 0, 1, 50},
+		// Ticks run flat out while frames are drawn when they can be
+		{"sampled", 1000, 100, 50},
+	}
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		r := &slowRenderer{delay: 5 * time.Millisecond}
+		g, err := New(WithRenderer(r), WithTickDuration(0), WithFrameRate(tc.fps))
+		if err != nil {
+			t.Fatalf("New: %s", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		err = g.Run(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Got %v expected %v", err, context.DeadlineExceeded)
+		}
+		frames := atomic.LoadInt64(&r.frames)
+		if frames < 2 || frames > tc.maxFrames {
+			t.Fatalf("Got %v expected 2 to %v frames", frames, tc.maxFrames)
+		}
+		if g.Tick() < tc.minTicks {
+			t.Fatalf("Got %v expected at least %v ticks", g.Tick(), tc.minTicks)
+		}
+		if tc.fps == 0 && int64(g.Tick()) != frames {
+			t.Fatalf("Got %v frames expected one per tick, %v", frames, g.Tick())
+		}
+	}
+
+	_, err := New(WithFrameRate(-1))
+	if err == nil {
+		t.Fatalf("expected an error for a negative frame rate")
+	}
+}
+
+// Step draws each tick whatever the frame rate, as there is no clock
+func TestStepDraws(t *testing.T) {
+	r := &slowRenderer{}
+	g, err := New(WithRenderer(r), WithFrameRate(30))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		err = g.Step()
+		if err != nil {
+			t.Fatalf("Step: %s", err)
+		}
+	}
+	if r.frames != 3 {
+		t.Fatalf("Got %v expected 3 frames", r.frames)
+	}
+}
+
+// Frames copy what each entity draws, so kinds the core loop doesn't
+// know about are drawn too
+func TestFrameDrawsCustomKinds(t *testing.T) {
+	dl := &drawLog{}
+	g, err := New(WithRenderer(dl), WithTickDuration(0), WithFrameRate(1000))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	m := &marker{BaseEntity: NewBaseEntity(Pos{X: 3, Y: 3}), strength: 1e9}
+	id, err := g.AddEntity(m)
+	if err != nil {
+		t.Fatalf("AddEntity: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = g.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Got %v expected %v", err, context.DeadlineExceeded)
+	}
+
+	drawn := 0
+	for _, d := range dl.drawn {
+		e, ok := d.(interface{ ID() int64 })
+		if !ok || e.ID() != id {
+			continue
+		}
+		drawn++
+		_, _, b := d.Screen()
+		if b != '*' {
+			t.Fatalf("Got %q expected the marker's '*'", b)
+		}
+	}
+	if drawn < 2 {
+		t.Fatalf("Got the marker in %d frames expected at least 2", drawn)
+	}
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jbert/creech"
+	"github.com/jbert/creech/prom"
 	"github.com/jbert/creech/render"
 )
 
@@ -13,37 +24,329 @@ type options struct {
 	renderMode string
 	hostPort   string
 	tick       time.Duration
+	fps        float64
+	seed       int64
+	seedSet    bool // Otherwise a scenario's own seed is used
+	record     string
+	metrics    string
+	admin      string
+	config     string
+	scenario   string
+	logLevel   slog.Level
+	trace      int64
+	save       string
+
+	file render.FileOptions
+}
+
+// Flags common to running and replaying
+func addRenderFlags(fs *flag.FlagSet, o *options) {
+	fs.StringVar(&o.renderMode, "render", "screen", "render mode: 'screen', 'term', 'web' or 'file'")
+	fs.StringVar(&o.hostPort, "hostport", ":8080", "host:port for web mode")
+	fs.DurationVar(&o.tick, "tick", time.Second, "Tick duration, 0 for as fast as possible")
+	fs.Float64Var(&o.fps, "fps", 0, "frames drawn per second, independent of -tick, 0 to draw every tick")
+	fs.StringVar(&o.file.Dir, "out", "frames", "directory for frames in file mode")
+	fs.StringVar(&o.file.Format, "format", "svg", "frame format for file mode: 'svg', 'png' or '' for none")
+	fs.IntVar(&o.file.Every, "every", 1, "write every Nth frame in file mode")
+	fs.IntVar(&o.file.Frames, "frames", 0, "stop after writing this many frames in file mode, 0 for no limit")
+	fs.StringVar(&o.file.GIF, "gif", "", "also write an animated GIF to this path in file mode, needs -frames")
+	fs.StringVar(&o.admin, "admin", "", "host:port to serve /metrics and /debug/pprof on, web mode also serves /metrics on -hostport")
+	fs.TextVar(&o.logLevel, "log-level", slog.LevelInfo, "log level: 'debug', 'info', 'warn' or 'error'")
+}
+
+func setupLogging(o *options) {
+	h := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: o.logLevel})
+	slog.SetDefault(slog.New(h))
 }
 
-func flagsToOptions() *options {
+func fatalf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func flagsToOptions(args []string) *options {
 	var o options
-	flag.StringVar(&o.renderMode, "render", "screen", "render mode: 'screen' or 'web'")
-	flag.StringVar(&o.hostPort, "hostport", ":8080", "host:port for web mode")
-	flag.DurationVar(&o.tick, "tick", time.Second, "Tick duration")
-	flag.Parse()
+	fs := flag.NewFlagSet("creech", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: creech [flags]\n       creech replay [flags] <recording>\n       creech verify <recording>\n")
+		fs.PrintDefaults()
+	}
+	addRenderFlags(fs, &o)
+	fs.Int64Var(&o.seed, "seed", time.Now().UnixNano(), "random seed for the simulation")
+	fs.StringVar(&o.record, "record", "", "record the run to this file, gzipped if it ends in .gz")
+	fs.StringVar(&o.config, "config", "", "JSON file describing the world, creeches, food and energy costs")
+	fs.StringVar(&o.scenario, "scenario", "", "JSON scenario file with a config and scheduled events, instead of -config")
+	fs.StringVar(&o.metrics, "metrics", "", "write per-tick metrics to this .csv or .jsonl file")
+	fs.Int64Var(&o.trace, "trace-entity", 0, "log every decision made by the creech with this ID")
+	fs.StringVar(&o.save, "save", "", "on exit, save the world to this file as a one frame recording for replay")
+	fs.Parse(args)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			o.seedSet = true
+		}
+	})
 	return &o
 }
 
-func main() {
-	o := flagsToOptions()
-
-	var r render.Renderer
+func makeRenderer(o *options) (render.Renderer, error) {
 	switch o.renderMode {
 	case "screen":
-		r = render.NewScreen()
+		return render.NewScreen(), nil
+	case "term":
+		return render.NewTerminal(), nil
 	case "web":
-		r = render.NewWeb(o.hostPort)
+		return render.NewWeb(o.hostPort), nil
+	case "file":
+		f, err := render.NewFile(o.file)
+		if err != nil {
+			return nil, fmt.Errorf("Can't render to file: %w", err)
+		}
+		return f, nil
 	default:
-		log.Fatalf("Unknown render mode: %s", o.renderMode)
+		return nil, fmt.Errorf("Unknown render mode: %s", o.renderMode)
+	}
+}
+
+// Prometheus metrics and profiling
+func addAdminHandlers(mux *http.ServeMux, reg *prom.Registry) {
+	mux.Handle("/metrics", reg)
+	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
+	mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	mux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+	mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+}
+
+// Serves stats until stop is called
+func serveStats(o *options, game *creech.Game, r render.Renderer) (stop func(), err error) {
+	reg := prom.NewRegistry()
+	game.RegisterStats(reg)
+	if w, ok := r.(*render.Web); ok {
+		w.RegisterStats(reg)
+		// Profiling stays on the admin port, away from web clients
+		w.Handle("/metrics", reg)
+	}
+	if o.admin == "" {
+		return func() {}, nil
+	}
+	ln, err := net.Listen("tcp", o.admin)
+	if err != nil {
+		return nil, fmt.Errorf("Can't serve admin: %w", err)
+	}
+	mux := http.NewServeMux()
+	addAdminHandlers(mux, reg)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Can't serve admin", "addr", o.admin, "err", err)
+		}
+	}()
+	return func() { srv.Close() }, nil
+}
+
+// Close the renderer if it holds anything open, e.g. websockets or a GIF
+func closeRenderer(r render.Renderer) {
+	c, ok := r.(io.Closer)
+	if !ok {
+		return
+	}
+	err := c.Close()
+	if err != nil {
+		slog.Error("Can't close renderer", "err", err)
+	}
+}
+
+// Cancelled by an interrupt or termination, to shut down cleanly. Only
+// the first signal is caught, so a second kills a shutdown which hangs.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			replayMain(os.Args[2:])
+			return
+		case "verify":
+			verifyMain(os.Args[2:])
+			return
+		}
 	}
 
-	game := creech.NewGame(r, o.tick)
-	err := game.Init()
+	o := flagsToOptions(os.Args[1:])
+	setupLogging(o)
+	err := run(o)
+	if err != nil {
+		fatalf("%s", err)
+	}
+}
+
+// Returns rather than exiting, so recordings and metrics are flushed
+func run(o *options) error {
+	if o.config != "" && o.scenario != "" {
+		return errors.New("Use one of -config and -scenario")
+	}
+	var sc *creech.Scenario
+	if o.scenario != "" {
+		var err error
+		sc, err = creech.LoadScenario(o.scenario)
+		if err != nil {
+			return fmt.Errorf("Can't load scenario: %w", err)
+		}
+		if !o.seedSet {
+			o.seed = sc.Seed
+		}
+	}
+
+	r, err := makeRenderer(o)
+	if err != nil {
+		return err
+	}
+	defer closeRenderer(r)
+	opts := []creech.Option{creech.WithRenderer(r), creech.WithTickDuration(o.tick), creech.WithFrameRate(o.fps), creech.WithTrace(o.trace)}
+	if sc != nil {
+		opts = append(opts, creech.WithScenario(sc))
+	}
+	if o.config != "" {
+		cfg, err := creech.LoadConfig(o.config)
+		if err != nil {
+			return fmt.Errorf("Can't load config: %w", err)
+		}
+		opts = append(opts, creech.WithConfig(cfg))
+	}
+	// After the scenario, which brings its own seed
+	opts = append(opts, creech.WithSeed(o.seed))
+	game, err := creech.New(opts...)
+	if err != nil {
+		return fmt.Errorf("Can't start: %w", err)
+	}
+	stopStats, err := serveStats(o, game, r)
+	if err != nil {
+		return err
+	}
+	defer stopStats()
+	if o.record != "" {
+		recorder, err := creech.NewRecorder(o.record, game.RecordingHeader())
+		if err != nil {
+			return fmt.Errorf("Can't record: %w", err)
+		}
+		defer func() {
+			err := recorder.Close()
+			if err != nil {
+				slog.Error("Can't close recording", "err", err)
+			}
+		}()
+		err = game.SetRecorder(recorder)
+		if err != nil {
+			return err
+		}
+	}
+	if o.metrics != "" {
+		m, err := creech.NewMetricsWriter(o.metrics)
+		if err != nil {
+			return fmt.Errorf("Can't write metrics: %w", err)
+		}
+		defer func() {
+			err := m.Close()
+			if err != nil {
+				slog.Error("Can't close metrics", "err", err)
+			}
+		}()
+		game.SetMetrics(m)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	err = game.Run(ctx)
+	if o.save != "" {
+		saveErr := game.Save(o.save)
+		if saveErr != nil {
+			slog.Error("Can't save", "path", o.save, "err", saveErr)
+		} else {
+			slog.Info("Saved", "path", o.save, "tick", game.Tick())
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		slog.Info("Stopped", "tick", game.Tick())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Exit with error: %w", err)
+	}
+	if o.scenario != "" {
+		err = game.CheckExpectations()
+		if err != nil {
+			return fmt.Errorf("Scenario failed: %w", err)
+		}
+		slog.Info("Scenario passed")
+	}
+	return nil
+}
+
+func replayMain(args []string) {
+	var o options
+	fs := flag.NewFlagSet("creech replay", flag.ExitOnError)
+	addRenderFlags(fs, &o)
+	fs.Parse(args)
+	setupLogging(&o)
+	if fs.NArg() != 1 {
+		fatalf("Usage: creech replay [flags] <recording>")
+	}
+	err := replay(&o, fs.Arg(0))
+	if err != nil {
+		fatalf("%s", err)
+	}
+}
+
+func replay(o *options, path string) error {
+	rec, err := creech.LoadRecording(path)
+	if err != nil {
+		return fmt.Errorf("Can't load recording: %w", err)
+	}
+	r, err := makeRenderer(o)
+	if err != nil {
+		return err
+	}
+	defer closeRenderer(r)
+	game := creech.NewReplayGame(r, o.tick, rec)
+	err = game.SetFrameRate(o.fps)
+	if err != nil {
+		return err
+	}
+	err = game.Init()
+	if err != nil {
+		return fmt.Errorf("Init with error: %w", err)
+	}
+	stopStats, err := serveStats(o, game, r)
+	if err != nil {
+		return err
+	}
+	defer stopStats()
+
+	ctx, stop := signalContext()
+	defer stop()
+	err = game.Run(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("Exit with error: %w", err)
+	}
+	return nil
+}
+
+func verifyMain(args []string) {
+	if len(args) != 1 {
+		fatalf("Usage: creech verify <recording>")
+	}
+	rec, err := creech.LoadRecording(args[0])
 	if err != nil {
-		log.Fatalf("Init with error: %s", err)
+		fatalf("Can't load recording: %s", err)
 	}
-	err = game.Run()
+	err = creech.Verify(rec)
 	if err != nil {
-		log.Fatalf("Exit with error: %s", err)
+		fatalf("Verify failed: %s", err)
 	}
+	fmt.Printf("%d frames match\n", len(rec.Frames))
 }
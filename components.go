@@ -0,0 +1,190 @@
+package creech
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// Entities are made of components, each of which is an interface.
+// Systems run each tick over the entities with the components they
+// need, so a new kind of entity implements the components it wants,
+// is added to the Registry, and the core loop needs no changes.
+//
+// Every Entity has a position and a body: it takes up room, so
+// nothing is placed on top of it, it can be seen, and it can hide
+// what is behind it.
+
+// Renderable entities are drawn, lower layers first
+type Renderable interface {
+	Entity
+	render.Drawable
+	Layer() int
+}
+
+// Energetic entities live on a store of energy
+type Energetic interface {
+	Entity
+	Energy() float64
+	Dead() bool
+}
+
+// Sensor entities see what is in their view
+type Sensor interface {
+	Entity
+	ViewRegion() Region
+}
+
+// Brain entities choose what to do, then do it, each tick
+type Brain interface {
+	Entity
+	Think(g *Game)
+	Act(g *Game)
+}
+
+// Edible entities can be eaten
+type Edible interface {
+	Entity
+	Consume(bite float64)
+}
+
+// Grower entities change by themselves each tick
+type Grower interface {
+	Entity
+	Grow(g *Game)
+}
+
+// Entities embedding BaseEntity can be moved
+type placed interface {
+	Entity
+	setPos(p Pos)
+}
+
+func alive(e Entity) bool {
+	en, ok := e.(Energetic)
+	return !ok || !en.Dead()
+}
+
+// A System runs once per tick
+type System func(g *Game)
+
+// Systems run in this order, see Game.AddSystem for more
+func defaultSystems() []System {
+	return []System{thinkSystem, actSystem, growSystem, spawnFoodSystem}
+}
+
+// Live brains come back into the world and decide what to do
+func thinkSystem(g *Game) {
+	for _, b := range All[Brain](&g.state.entities) {
+		if !alive(b) {
+			continue
+		}
+		err := g.state.moveEntity(b, g.wrap(b.Pos()))
+		if err != nil {
+			g.log.Warn("Can't keep in the world", "tick", g.ticks, "id", b.ID(), "err", err)
+			continue
+		}
+		b.Think(g)
+	}
+}
+
+// Brains, live or dead, carry out their plans
+func actSystem(g *Game) {
+	for _, b := range All[Brain](&g.state.entities) {
+		wasAlive := alive(b)
+		b.Act(g)
+		// Walls stop a creech as it moves, a torus wraps at the next tick
+		if g.config.World.Topology == TopologyBounded {
+			err := g.state.moveEntity(b, clampPos(b.Pos(), g.worldSize))
+			if err != nil {
+				g.log.Warn("Can't keep in the world", "tick", g.ticks, "id", b.ID(), "err", err)
+			}
+		}
+		if wasAlive && !alive(b) {
+			g.events.Publish(DiedEvent{g.eventHeader(b.ID()), DeathStarved})
+		}
+	}
+}
+
+func growSystem(g *Game) {
+	for _, gr := range All[Grower](&g.state.entities) {
+		gr.Grow(g)
+	}
+}
+
+func spawnFoodSystem(g *Game) {
+	g.foodSpawner.SpawnFood(g)
+}
+
+// Renderables in the order to draw them
+func (s *State) renderables() []Renderable {
+	rs := All[Renderable](&s.entities)
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Layer() < rs[j].Layer()
+	})
+	return rs
+}
+
+// Layers for the built in kinds
+const (
+	layerFood = iota
+	layerCreech
+)
+
+func (c *Creech) Layer() int {
+	return layerCreech
+}
+
+func (f *Food) Layer() int {
+	return layerFood
+}
+
+func (c *Creech) Energy() float64 {
+	return c.food
+}
+
+// Think sets the view for the conditions here, and makes a plan with
+// the species' Mind if it has one
+func (c *Creech) Think(g *Game) {
+	c.viewScale = g.EnvironmentAt(c.pos).ViewScale
+	// Seen whichever brain plans, so SawEvent comes from every creech
+	entities := c.observe(g)
+	if m, ok := g.minds[c.params.Species]; ok {
+		c.plan = m.Plan(g, c)
+		if tr := g.tracer(c.ID()); tr != nil {
+			name := "NONE"
+			if c.plan != nil {
+				name = c.plan.name
+			}
+			tr.Info("Mind chose plan", "plan", name)
+		}
+		return
+	}
+	c.planFrom(g, entities)
+}
+
+// Act carries out the plan, spending food on it and on keeping warm
+// or cool
+func (c *Creech) Act(g *Game) {
+	before, plan := c.food, c.plan
+	c.DoPlan(g.config.Energy, g.state.terrain, g.EnvironmentAt(c.pos).Metabolism)
+	if tr := g.tracer(c.ID()); tr != nil && plan != nil {
+		tr.Info("Acted", "plan", plan.name, "cost", plan.Cost(),
+			"food_before", before, "food_after", c.food, "pos", c.pos, "facing", c.facing)
+	}
+}
+
+// Grow regrows food, faster on good ground and in a good season
+func (f *Food) Grow(g *Game) {
+	fc := g.config.Food
+	if fc.RegrowRate <= 0 {
+		return
+	}
+	season := g.EnvironmentAt(f.pos).FoodGrowth
+	growth := fc.RegrowRate * season * g.state.terrain.At(f.pos).FoodGrowth
+	f.value = math.Min(f.value+growth, fc.MaxValue)
+}
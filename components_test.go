@@ -0,0 +1,119 @@
+package creech
+
+import (
+	"testing"
+
+	"github.com/jbert/creech/render"
+
+	. "github.com/jbert/creech/pos"
+)
+
+// A kind the core loop knows nothing about: a scent which fades away
+type marker struct {
+	BaseEntity
+	strength float64
+}
+
+func (m *marker) Size() float64             { return 0.5 }
+func (m *marker) Layer() int                { return -1 }
+func (m *marker) Screen() (int, int, byte)  { return 0, 0, '*' }
+func (m *marker) Web() []render.DrawCommand { return nil }
+
+func (m *marker) Grow(g *Game) {
+	m.strength--
+	if m.strength <= 0 {
+		g.state.entities.Remove(m.ID())
+	}
+}
+
+// Just a body, in the way and ignored by brains
+type rock struct {
+	BaseEntity
+}
+
+func (r *rock) Size() float64 { return 2 }
+
+type drawLog struct {
+	render.Null
+	drawn []render.Drawable
+}
+
+func (d *drawLog) Draw(dr render.Drawable) error {
+	d.drawn = append(d.drawn, dr)
+	return nil
+}
+
+func TestNewKinds(t *testing.T) {
+	dl := &drawLog{}
+	g := NewGame(dl, 0, 1)
+	err := g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	bob := g.state.creeches()[0]
+	m := &marker{BaseEntity: NewBaseEntity(bob.Pos().Add(Pos{X: 0, Y: 2})), strength: 3}
+	g.state.entities.add(m)
+	r := &rock{BaseEntity: NewBaseEntity(bob.Pos().Add(Pos{X: 0, Y: 4}))}
+	g.state.entities.add(r)
+
+	err = g.draw()
+	if err != nil {
+		t.Fatalf("draw: %s", err)
+	}
+	if len(dl.drawn) == 0 || dl.drawn[0] != render.Drawable(m) {
+		t.Fatalf("expected the marker drawn first, got %v", dl.drawn)
+	}
+
+	for i := 0; i < 3; i++ {
+		g.advance()
+	}
+	if g.state.entities.Get(m.ID()) != nil {
+		t.Fatalf("marker didn't fade away")
+	}
+	if g.state.entities.Get(r.ID()) == nil {
+		t.Fatalf("lost the rock")
+	}
+}
+
+func TestAddSystem(t *testing.T) {
+	g := NewGame(render.NewNull(), 0, 1)
+	err := g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	ran := 0
+	g.AddSystem(func(g *Game) { ran++ })
+	g.advance()
+	g.advance()
+	if ran != 2 {
+		t.Fatalf("system ran %d times expected 2", ran)
+	}
+}
+
+func TestSnapshotOthers(t *testing.T) {
+	g, err := New()
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	r := &rock{BaseEntity: NewBaseEntity(Pos{X: 3, Y: 4})}
+	id, err := g.AddEntity(r)
+	if err != nil {
+		t.Fatalf("AddEntity: %s", err)
+	}
+
+	snap := g.Snapshot()
+	expected := []EntitySnapshot{{ID: id, Kind: "rock", Pos: Pos{X: 3, Y: 4}, Size: 2}}
+	if len(snap.Others) != 1 || snap.Others[0] != expected[0] {
+		t.Fatalf("Got %v expected %v", snap.Others, expected)
+	}
+
+	// Rebuilt in the way, as the rock was
+	s, err := stateFromSnapshot(snap)
+	if err != nil {
+		t.Fatalf("stateFromSnapshot: %s", err)
+	}
+	e := s.entities.Get(id)
+	if e == nil || e.Pos() != r.Pos() || e.Size() != r.Size() {
+		t.Fatalf("Got %v expected a stand in for the rock", e)
+	}
+}
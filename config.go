@@ -0,0 +1,252 @@
+package creech
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// Config is the world a run starts with and the rules it follows. It
+// is loaded from JSON, anything left out keeps its default.
+type Config struct {
+	World       WorldConfig
+	Obstacles   []ObstacleConfig
+	Terrain     []TerrainConfig
+	Creeches    []PopulationConfig
+	Food        FoodConfig
+	Energy      EnergyConfig
+	Environment EnvironmentConfig
+}
+
+// Topologies
+const (
+	TopologyTorus   = "torus"   // Leaving one edge enters at the opposite edge
+	TopologyBounded = "bounded" // Edges are walls
+)
+
+// The world is centred on the origin
+type WorldConfig struct {
+	Width    float64
+	Height   float64
+	Topology string
+}
+
+// A group of creeches sharing a name and parameters
+type PopulationConfig struct {
+	Name    string
+	Species string // Default from DefaultParams
+	Count   int
+	Pos     []Pos              // Where the first len(Pos) start, the rest are placed randomly
+	Params  map[string]float64 // Overrides of DefaultParams
+}
+
+type FoodConfig struct {
+	Count      int     // At the start
+	MinValue   float64 // Initial values are uniform in [MinValue, MaxValue)
+	MaxValue   float64
+	RegrowRate float64 // Value added to each food per tick, up to MaxValue
+	SpawnEvery int     // Ticks between new food, 0 for never
+	MaxCount   int     // No new food while there is this much
+}
+
+// Food spent by creeches
+type EnergyConfig struct {
+	Plan float64 // Per plan executed
+	Move float64 // Per metre moved
+	Turn float64 // Per radian turned
+}
+
+func DefaultConfig() Config {
+	return Config{
+		World: WorldConfig{
+			Width:    40,
+			Height:   40,
+			Topology: TopologyTorus,
+		},
+		Creeches: []PopulationConfig{
+			{Name: "bob", Count: 1, Pos: []Pos{{X: 0, Y: 0}}},
+			{Name: "alice", Count: 1, Pos: []Pos{{X: 2, Y: 2}}},
+		},
+		Food: FoodConfig{
+			Count:    5,
+			MinValue: 0,
+			MaxValue: 10,
+		},
+		Energy: EnergyConfig{
+			Plan: 0.1,
+		},
+		Environment: EnvironmentConfig{
+			NightView: 1,
+		},
+	}
+}
+
+// LoadConfig reads a JSON config over the defaults
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	err := decodeFile(path, &cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// UnmarshalJSON starts from the defaults, so a config need only give
+// what it changes
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config // Without this method, so Decode doesn't recurse
+	cfg := plain(DefaultConfig())
+	// Decoding would merge into the default populations, rather than
+	// replacing them
+	cfg.Creeches = nil
+	err := strictDecode(bytes.NewReader(data), &cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Creeches == nil {
+		cfg.Creeches = DefaultConfig().Creeches
+	}
+	*c = Config(cfg)
+	return nil
+}
+
+func decodeFile(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("can't open %s: %w", path, err)
+	}
+	defer f.Close()
+	err = strictDecode(f, v)
+	if err != nil {
+		return fmt.Errorf("can't parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// A typo should be an error, not a silently ignored setting
+func strictDecode(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func (c *Config) WorldSize() Pos {
+	return Pos{X: c.World.Width, Y: c.World.Height}
+}
+
+func (c *Config) Validate() error {
+	w := c.World
+	if !(w.Width > 0) || !(w.Height > 0) {
+		return fmt.Errorf("world: size must be positive, got %gx%g", w.Width, w.Height)
+	}
+	if w.Topology != TopologyTorus && w.Topology != TopologyBounded {
+		return fmt.Errorf("world: unknown topology %q, want %q or %q", w.Topology, TopologyTorus, TopologyBounded)
+	}
+
+	for i, oc := range c.Obstacles {
+		err := validatePolygon(oc.Points)
+		if err != nil {
+			return fmt.Errorf("obstacles[%d]: %w", i, err)
+		}
+	}
+	for i, tc := range c.Terrain {
+		err := tc.validate()
+		if err != nil {
+			return fmt.Errorf("terrain[%d]: %w", i, err)
+		}
+	}
+
+	terrain := NewTerrain(c.Obstacles, nil)
+	for i, pc := range c.Creeches {
+		err := pc.validate(c.WorldSize(), terrain)
+		if err != nil {
+			return fmt.Errorf("creeches[%d]: %w", i, err)
+		}
+	}
+
+	err := c.Food.validate()
+	if err != nil {
+		return fmt.Errorf("food: %w", err)
+	}
+
+	e := c.Energy
+	if e.Plan < 0 || e.Move < 0 || e.Turn < 0 {
+		return fmt.Errorf("energy: costs must not be negative, got %+v", e)
+	}
+
+	err = c.Environment.validate()
+	if err != nil {
+		return fmt.Errorf("environment: %w", err)
+	}
+	return nil
+}
+
+func (pc *PopulationConfig) validate(worldSize Pos, terrain *Terrain) error {
+	if pc.Name == "" {
+		return errors.New("name must be set")
+	}
+	if pc.Count < 1 {
+		return fmt.Errorf("count must be at least 1, got %d", pc.Count)
+	}
+	if len(pc.Pos) > pc.Count {
+		return fmt.Errorf("%d positions for %d creeches", len(pc.Pos), pc.Count)
+	}
+	for _, p := range pc.Pos {
+		if math.Abs(p.X) > worldSize.X/2 || math.Abs(p.Y) > worldSize.Y/2 {
+			return fmt.Errorf("position %s is outside the world", p)
+		}
+		if terrain.Blocked(p) {
+			return fmt.Errorf("position %s is inside an obstacle", p)
+		}
+	}
+	_, err := pc.params()
+	return err
+}
+
+func (pc *PopulationConfig) params() (Params, error) {
+	p := DefaultParams()
+	if pc.Species != "" {
+		p.Species = pc.Species
+	}
+	return p.With(pc.Params)
+}
+
+// Name of the i'th creech, numbered only if there are several
+func (pc *PopulationConfig) name(i int) string {
+	if pc.Count == 1 {
+		return pc.Name
+	}
+	return fmt.Sprintf("%s-%d", pc.Name, i+1)
+}
+
+func (fc *FoodConfig) randomValue(rnd *rand.Rand) float64 {
+	return fc.MinValue + rnd.Float64()*(fc.MaxValue-fc.MinValue)
+}
+
+func (fc *FoodConfig) validate() error {
+	if fc.Count < 0 || fc.MaxCount < 0 || fc.SpawnEvery < 0 {
+		return fmt.Errorf("counts must not be negative, got %+v", *fc)
+	}
+	if fc.MinValue < 0 || fc.MinValue > fc.MaxValue {
+		return fmt.Errorf("values must satisfy 0 <= MinValue <= MaxValue, got %g and %g", fc.MinValue, fc.MaxValue)
+	}
+	if (fc.Count > 0 || fc.SpawnEvery > 0) && !(fc.MaxValue > 0) {
+		return fmt.Errorf("MaxValue must be positive to have food, got %g", fc.MaxValue)
+	}
+	if fc.RegrowRate < 0 {
+		return fmt.Errorf("RegrowRate must not be negative, got %g", fc.RegrowRate)
+	}
+	return nil
+}
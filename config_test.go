@@ -0,0 +1,105 @@
+package creech
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbert/creech/render"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("configs/crowded.json")
+	if err != nil {
+		t.Fatalf("LoadConfig: %s", err)
+	}
+
+	g := NewGame(render.NewNull(), time.Millisecond, 42)
+	err = g.SetConfig(cfg)
+	if err != nil {
+		t.Fatalf("SetConfig: %s", err)
+	}
+	err = g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	if len(g.state.creeches()) != 10 {
+		t.Fatalf("got %d creeches expected 10", len(g.state.creeches()))
+	}
+	if g.state.creeches()[2].name != "grazer-2" || g.state.creeches()[2].params.Species != "grazer" {
+		t.Fatalf("got creech %s of species %s", g.state.creeches()[2].name, g.state.creeches()[2].params.Species)
+	}
+
+	for i := 0; i < 500; i++ {
+		g.advance()
+	}
+	for _, c := range g.state.creeches() {
+		if math.Abs(c.pos.X) > 30 || math.Abs(c.pos.Y) > 20 {
+			t.Fatalf("%s escaped a bounded world", c)
+		}
+	}
+	if len(g.state.food()) > cfg.Food.MaxCount {
+		t.Fatalf("got %d food, max is %d", len(g.state.food()), cfg.Food.MaxCount)
+	}
+}
+
+func TestLoadTerrainConfig(t *testing.T) {
+	cfg, err := LoadConfig("configs/terrain.json")
+	if err != nil {
+		t.Fatalf("LoadConfig: %s", err)
+	}
+	g := NewGame(render.NewNull(), time.Millisecond, 42)
+	err = g.SetConfig(cfg)
+	if err != nil {
+		t.Fatalf("SetConfig: %s", err)
+	}
+	err = g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	for i := 0; i < 500; i++ {
+		g.advance()
+		for _, c := range g.state.creeches() {
+			if g.state.terrain.Blocked(c.pos) {
+				t.Fatalf("%s inside an obstacle at tick %d", c, g.ticks)
+			}
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	testCases := []struct {
+		json     string
+		expected string
+	}{
+		{`{"World": {"Width": 0}}`, "world: size must be positive"},
+		{`{"World": {"Topology": "klein"}}`, "unknown topology"},
+		{`{"Creeches": [{"Name": "bob"}]}`, "creeches[0]: count must be at least 1"},
+		{`{"Creeches": [{"Name": "bob", "Count": 1, "Params": {"Size": -1}}]}`, "parameter Size must be positive"},
+		{`{"Creeches": [{"Name": "bob", "Count": 1, "Params": {"Speed": 1}}]}`, "unknown parameter: Speed"},
+		{`{"Creeches": [{"Name": "bob", "Count": 1, "Pos": [{"X": 100, "Y": 0}]}]}`, "outside the world"},
+		{`{"Obstacles": [{"Points": [{"X": -5, "Y": -5}, {"X": 5, "Y": -5}, {"X": 0, "Y": 5}]}], "Creeches": [{"Name": "bob", "Count": 1, "Pos": [{"X": 0, "Y": 0}]}]}`, "creeches[0]: position [0.00000,0.00000] is inside an obstacle"},
+		{`{"Food": {"MinValue": 5, "MaxValue": 1}}`, "food: values must satisfy"},
+		{`{"Energy": {"Move": -1}}`, "energy: costs must not be negative"},
+		{`{"Obstacles": [{"Points": [{"X": 0, "Y": 0}, {"X": 1, "Y": 0}]}]}`, "obstacles[0]: need at least 3 points, got 2"},
+		{`{"Terrain": [{"Type": "lava", "Points": []}]}`, `terrain[0]: unknown terrain type "lava"`},
+		{`{"Environment": {"NightView": 0}}`, "environment: NightView must be in (0, 1], got 0"},
+		{`{"Wrold": {}}`, "unknown field"},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range testCases {
+		path := filepath.Join(dir, "config.json")
+		err := os.WriteFile(path, []byte(tc.json), 0644)
+		if err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		_, err = LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Fatalf("%s: got error %v expected %q", tc.json, err, tc.expected)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package creech // import "github.com/jbert/creech"
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"math/rand"
 	"sort"
@@ -15,159 +18,731 @@ import (
 )
 
 type Game struct {
+	config    Config
 	worldSize Pos
-	tickDur   time.Duration
+	tickDur   time.Duration // Zero ticks as fast as possible
+	frameDur  time.Duration // Zero draws every tick
 	renderer  render.Renderer
+	drawnSize Pos // The world size the renderer last drew
+
+	sampler *frameSampler // Drawing frames, while Run has a frame rate
+	changed bool          // Since the last frame was sampled
 
 	state State
+	seed  int64
+
+	replay *Recording // Playing back, rather than simulating
+	life   lifeCounts
+	stats  *gameStats // May be nil
+
+	systems     []System        // Run in order each tick
+	minds       map[string]Mind // By species, may be nil
+	foodSpawner FoodSpawner
+	events      Bus
+	failed      error // First error from a subscriber this tick
+	log         *slog.Logger
+	traceID     int64 // Creech whose decisions are logged, 0 for none
+
+	scenario  *Scenario // May be nil
+	nextEvent int       // Index of the next scenario event to fire
+
+	ticks    int
+	paused   bool
+	selected Entity // Being inspected, may be nil
 }
 
 type State struct {
-	creeches []*Creech
-	food     []*Food
+	entities Registry
+	terrain  *Terrain // Never nil
+
+	// All randomness in the simulation comes from here, so a seed
+	// reproduces a run
+	rand *rand.Rand
+}
+
+func (s *State) creeches() []*Creech {
+	return All[*Creech](&s.entities)
+}
+
+func (s *State) food() []*Food {
+	return All[*Food](&s.entities)
 }
 
 func (s *State) String() string {
 	var lines []string
-	for _, c := range s.creeches {
-		lines = append(lines, c.String())
-	}
-	for _, f := range s.food {
-		lines = append(lines, f.String())
+	for _, e := range s.entities.Entities() {
+		lines = append(lines, fmt.Sprint(e))
 	}
 	return strings.Join(lines, "\n")
 }
 
-func (s *State) AddCreeches() {
-	bob := NewCreech("bob", Pos{0, 0})
-	s.creeches = append(s.creeches, bob)
+func (s *State) AddCreeches(pops []PopulationConfig, worldSize Pos) error {
+	for _, pc := range pops {
+		params, err := pc.params()
+		if err != nil {
+			return fmt.Errorf("Can't add %s: %w", pc.Name, err)
+		}
+		for i := 0; i < pc.Count; i++ {
+			c := NewCreech(pc.name(i), Pos{X: 0, Y: 0}, params)
+			if i < len(pc.Pos) {
+				c.pos = pc.Pos[i]
+			} else {
+				err = c.SetRandomPos(s, worldSize, c.Size())
+				if err != nil {
+					return fmt.Errorf("Can't place %s: %w", c.name, err)
+				}
+			}
+			s.entities.add(c)
+		}
+	}
+	return nil
+}
 
-	alice := NewCreech("alice", Pos{2, 2})
-	s.creeches = append(s.creeches, alice)
+func (s *State) AddFood(fc FoodConfig, worldSize Pos) error {
+	for i := 0; i < fc.Count; i++ {
+		f := NewFood(fc.randomValue(s.rand))
+		err := f.SetRandomPos(s, worldSize, f.Size())
+		if err != nil {
+			return fmt.Errorf("Can't place food %d: %w", i, err)
+		}
+		s.entities.add(f)
+	}
+	return nil
 }
 
-func (s *State) AddFood(worldSize Pos) {
-	numFood := 5
-	for i := 0; i < numFood; i++ {
-		value := rand.Float64() * 10
-		f := NewFood(value)
-		f.SetRandomPos(s, worldSize, f.Size())
-		s.food = append(s.food, f)
+// Spawn more food, once per tick
+func (s *State) spawnFood(fc FoodConfig, worldSize Pos, ticks int) {
+	if fc.SpawnEvery > 0 && ticks%fc.SpawnEvery == 0 && Count[*Food](&s.entities) < fc.MaxCount {
+		// A crowded world may have no room, try again next time
+		f := NewFood(fc.randomValue(s.rand))
+		for try := 0; try < 10; try++ {
+			p, ok := s.tryRandomEmptyPos(worldSize, f.Size())
+			if ok && s.terrain.At(p).FoodGrowth > 0 {
+				f.pos = p
+				s.entities.add(f)
+				break
+			}
+		}
 	}
 }
 
 // TODO: at a certain point, we'll want to avoid looping over everything to do this
-func (s *State) randomEmptyPos(worldSize Pos, size float64) Pos {
-RANDOM_POSITION:
-	for {
-		p := Pos{rand.Float64() * worldSize.X, rand.Float64() * worldSize.Y}
-		p = moduloPos(p, worldSize)
-		for _, c := range s.creeches {
-			if c.pos.Near(p, c.Size()+size) {
-				continue RANDOM_POSITION
-			}
+// Placing too much in too small a world would otherwise never finish
+const maxPlacementTries = 10000
+
+func (s *State) randomEmptyPos(worldSize Pos, size float64) (Pos, error) {
+	for try := 0; try < maxPlacementTries; try++ {
+		p, ok := s.tryRandomEmptyPos(worldSize, size)
+		if ok {
+			return p, nil
 		}
-		for _, f := range s.food {
-			if f.Pos().Near(p, f.Size()+size) {
-				continue RANDOM_POSITION
-			}
+	}
+	return Pos{}, fmt.Errorf("no room after %d tries", maxPlacementTries)
+}
+
+// One guess at an empty position, false if it is occupied
+func (s *State) tryRandomEmptyPos(worldSize Pos, size float64) (Pos, bool) {
+	p := Pos{X: s.rand.Float64() * worldSize.X, Y: s.rand.Float64() * worldSize.Y}
+	p = moduloPos(p, worldSize)
+	if s.terrain.Blocked(p) {
+		return p, false
+	}
+	for _, e := range s.entities.Entities() {
+		if e.Pos().Near(p, e.Size()+size) {
+			return p, false
 		}
-		return p
 	}
+	return p, true
 }
 
-func (s *State) Draw(r render.Renderer, ticks int) error {
-	dlog := func(s string, args ...interface{}) {
-		//		log.Printf(s, args...)
+func (s *State) Draw(r render.Renderer, background []render.DrawCommand) error {
+	rs := s.renderables()
+	ds := make([]render.Drawable, len(rs))
+	for i, e := range rs {
+		ds[i] = e
 	}
-	dlog("StartFrame")
+	return drawFrame(r, background, s.terrain, ds)
+}
+
+// Draw the background, terrain and then the drawables, in order
+func drawFrame(r render.Renderer, background []render.DrawCommand, terrain *Terrain, ds []render.Drawable) error {
 	err := r.StartFrame()
 	if err != nil {
 		return fmt.Errorf("StartFrame: %w", err)
 	}
 
-	for i, f := range s.food {
-		dlog("Draw Food %d", i)
-		err = r.Draw(f)
+	if b, ok := r.(render.BackgroundDrawer); ok && len(background) > 0 {
+		err = b.DrawBackground(background)
+		if err != nil {
+			return fmt.Errorf("DrawBackground: %w", err)
+		}
+	}
+
+	// Terrain first, everything else is on top
+	for _, a := range terrain.areas {
+		err = r.Draw(a)
+		if err != nil {
+			return fmt.Errorf("Draw: %w", err)
+		}
+	}
+	for _, o := range terrain.obstacles {
+		err = r.Draw(o)
 		if err != nil {
 			return fmt.Errorf("Draw: %w", err)
 		}
 	}
 
-	for i, creech := range s.creeches {
-		dlog("Draw Creech %d", i)
-		err = r.Draw(creech)
+	for _, d := range ds {
+		err = r.Draw(d)
 		if err != nil {
 			return fmt.Errorf("Draw: %w", err)
 		}
 	}
 
-	dlog("FinishFrame")
 	err = r.FinishFrame()
 	if err != nil {
 		return fmt.Errorf("FinishFrame: %w", err)
 	}
-
-	fmt.Printf("%d ticks\n%s\n\n", ticks, s)
-	//	for _, creech := range s.creeches {
-	//		fmt.Printf("%s\n", creech)
-	//	}
-
 	return nil
 }
 
-func NewGame(r render.Renderer, tickDur time.Duration) *Game {
+func NewGame(r render.Renderer, tickDur time.Duration, seed int64) *Game {
+	cfg := DefaultConfig()
+	return &Game{
+		config:    cfg,
+		worldSize: cfg.WorldSize(),
+		tickDur:   tickDur,
+		renderer:  r,
+		seed:      seed,
+		systems:   defaultSystems(),
+		log:       slog.Default(),
+
+		foodSpawner: configFoodSpawner{},
+		state: State{
+			rand:    rand.New(rand.NewSource(seed)),
+			terrain: NewTerrain(cfg.Obstacles, cfg.Terrain),
+		},
+	}
+}
+
+// NewReplayGame plays back a recording rather than simulating
+func NewReplayGame(r render.Renderer, tickDur time.Duration, rec *Recording) *Game {
 	return &Game{
-		worldSize: Pos{40, 40},
+		config:    rec.Header.config(),
+		worldSize: rec.Header.WorldSize,
 		tickDur:   tickDur,
 		renderer:  r,
+		seed:      rec.Header.Seed,
+		replay:    rec,
+		log:       slog.Default(),
+	}
+}
+
+// SetConfig replaces the default config, call before Init
+func (g *Game) SetConfig(cfg Config) error {
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+	g.config = cfg
+	g.worldSize = cfg.WorldSize()
+	g.state.terrain = NewTerrain(cfg.Obstacles, cfg.Terrain)
+	return nil
+}
+
+// SetRecorder records the world as it is now, then after every tick
+func (g *Game) SetRecorder(r *Recorder) error {
+	err := r.Record(g.Snapshot())
+	if err != nil {
+		return fmt.Errorf("Can't Record: %w", err)
+	}
+	Subscribe(&g.events, func(StepEvent) {
+		err := r.Record(g.Snapshot())
+		if err != nil {
+			g.fail(fmt.Errorf("Can't Record: %w", err))
+		}
+	})
+	return nil
+}
+
+// SetMetrics samples metrics after every tick from now on
+func (g *Game) SetMetrics(m MetricsWriter) {
+	Subscribe(&g.events, func(StepEvent) {
+		err := m.Write(g.sample())
+		if err != nil {
+			g.fail(fmt.Errorf("Can't write metrics: %w", err))
+		}
+		g.life.reset()
+	})
+}
+
+// Subscribers can't return errors, so keep the first for tick to return
+func (g *Game) fail(err error) {
+	if g.failed == nil {
+		g.failed = err
 	}
 }
 
 func (g *Game) Init() error {
-	g.state.AddCreeches()
-	g.state.AddFood(g.worldSize)
+	if g.replay != nil {
+		if len(g.replay.Frames) == 0 {
+			return errors.New("Recording has no frames")
+		}
+		err := g.seek(0)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := g.state.AddCreeches(g.config.Creeches, g.worldSize)
+		if err != nil {
+			return err
+		}
+		err = g.state.AddFood(g.config.Food, g.worldSize)
+		if err != nil {
+			return err
+		}
+		// Creeches arriving and leaving after the start
+		g.state.entities.OnAdd(func(e Entity) {
+			if _, ok := e.(*Creech); ok {
+				g.events.Publish(BornEvent{g.eventHeader(e.ID())})
+			}
+		})
+		g.state.entities.OnRemove(func(e Entity) {
+			if c, ok := e.(*Creech); ok && !c.Dead() {
+				g.events.Publish(DiedEvent{g.eventHeader(e.ID()), DeathRemoved})
+			}
+		})
+		if _, ok := g.renderer.(render.Resizer); !ok && g.scenario != nil && g.scenario.resizes() {
+			return errors.New("Scenario resizes the world, which the renderer can't follow")
+		}
+		Subscribe(&g.events, func(BornEvent) { g.life.born() })
+		Subscribe(&g.events, func(e DiedEvent) { g.life.died(e.Cause) })
+		g.logEvents()
+	}
+	g.drawnSize = g.worldSize
 	return g.renderer.Init(g.worldSize.X, g.worldSize.Y)
 }
 
-func (g *Game) Run() error {
-	ticker := time.NewTicker(g.tickDur)
+// Header for a recording of this game
+func (g *Game) RecordingHeader() RecordingHeader {
+	cfg := g.config // Struct copy, events may change the live one
+	return RecordingHeader{
+		Version:   recordingVersion,
+		Seed:      g.seed,
+		WorldSize: g.worldSize,
+		TickDur:   g.tickDur,
+		Config:    &cfg,
+		Scenario:  g.scenario,
+	}
+}
+
+// Run ticks until the scenario ends, the renderer finishes, or ctx is
+// done, which returns ctx.Err()
+func (g *Game) Run(ctx context.Context) error {
+	// Idle until there is a tick duration
+	ticker := time.NewTicker(time.Hour)
 	defer ticker.Stop()
-	ticks := 0
+	if g.tickDur > 0 {
+		ticker.Reset(g.tickDur)
+	}
 
-	for range ticker.C {
-		err := g.state.Draw(g.renderer, ticks)
+	// A nil channel never receives, so without a frame rate there are
+	// no frames or draw errors, ticks draw themselves
+	var frames <-chan time.Time
+	var drawErrs <-chan error
+	if g.frameDur > 0 {
+		frameTicker := time.NewTicker(g.frameDur)
+		defer frameTicker.Stop()
+		frames = frameTicker.C
+		g.sampler = startSampler(g.renderer, g.drawnSize)
+		drawErrs = g.sampler.errs
+		g.sampler.offer(g.frame())
+		defer func() {
+			// Show how it ended
+			if g.changed {
+				g.sampler.offer(g.frame())
+			}
+			g.sampler.stop()
+			g.drawnSize = g.sampler.drawn
+			g.sampler = nil
+		}()
+	}
+
+	// Likewise, renderers without commands just tick
+	var cmds <-chan render.Command
+	if c, ok := g.renderer.(render.Commander); ok {
+		cmds = c.Commands()
+	}
+
+	for {
+		ticks := ticker.C
+		if g.paused {
+			ticks = nil
+		} else if g.tickDur == 0 {
+			ticks = alwaysReady
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticks:
+			err := g.tick()
+			if errors.Is(err, render.ErrFinished) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		case <-frames:
+			if g.changed {
+				g.changed = false
+				g.sampler.offer(g.frame())
+			}
+		case err := <-drawErrs:
+			if errors.Is(err, render.ErrFinished) {
+				return nil
+			}
+			return err
+		case cmd := <-cmds:
+			err := g.handleCommand(cmd, ticker)
+			if errors.Is(err, render.ErrFinished) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *Game) tick() error {
+	start := time.Now()
+	if g.sampler == nil {
+		err := g.draw()
 		if err != nil {
-			return fmt.Errorf("Can't Draw: %w", err)
+			return err
 		}
-		ticks++
+	}
+	g.advance()
+	g.changed = true
+	// Recording, metrics and stats all follow the event
+	g.events.Publish(StepEvent{g.eventHeader(0), time.Since(start)})
+	if g.failed != nil {
+		err := g.failed
+		g.failed = nil
+		return err
+	}
+	if g.scenarioEnded() {
+		return render.ErrFinished
+	}
+	return nil
+}
+
+// Move on one tick, by simulating or by playing back
+func (g *Game) advance() {
+	if g.replay == nil {
+		g.ticks++
 		g.Update()
+		g.runEvents()
+		return
+	}
+	if g.ticks+1 >= len(g.replay.Frames) {
+		// Hold the last frame
+		g.paused = true
+		return
+	}
+	err := g.seek(g.ticks + 1)
+	if err != nil {
+		g.paused = true
+		g.fail(err)
+	}
+}
+
+func (g *Game) seek(frame int) error {
+	s, err := stateFromSnapshot(g.replay.Frames[frame])
+	if err != nil {
+		return fmt.Errorf("Can't seek to frame %d: %w", frame, err)
+	}
+	g.ticks = frame
+	g.state = s
+	g.state.terrain = NewTerrain(g.config.Obstacles, g.config.Terrain)
+	// Entities are rebuilt, so select the new copy
+	if g.selected != nil {
+		g.selected = g.state.entities.Get(g.selected.ID())
+	}
+	return nil
+}
+
+func (g *Game) draw() error {
+	err := resize(g.renderer, &g.drawnSize, g.worldSize)
+	if err != nil {
+		return err
+	}
+	err = g.state.Draw(g.renderer, g.background())
+	if err != nil {
+		return fmt.Errorf("Can't Draw: %w", err)
+	}
+	g.log.Debug("Drew frame", "tick", g.ticks, "entities", g.state.entities.Len())
+	if i, ok := g.renderer.(render.Inspector); ok {
+		err = i.Inspect(g.inspect())
+		if err != nil {
+			return fmt.Errorf("Can't Inspect: %w", err)
+		}
 	}
 	return nil
 }
 
+func (g *Game) handleCommand(cmd render.Command, ticker *time.Ticker) error {
+	switch cmd.What {
+	case render.CmdPause:
+		g.paused = true
+	case render.CmdResume:
+		g.paused = false
+	case render.CmdStep:
+		g.paused = true
+		// A tick like any other, recorded, measured and published
+		err := g.tick()
+		if err != nil {
+			return err
+		}
+	case render.CmdSeek:
+		if g.replay == nil {
+			g.log.Warn("Can't seek, not a replay")
+			return nil
+		}
+		if cmd.Frame < 0 || cmd.Frame >= len(g.replay.Frames) {
+			g.log.Warn("Can't seek", "frame", cmd.Frame, "frames", len(g.replay.Frames))
+			return nil
+		}
+		err := g.seek(cmd.Frame)
+		if err != nil {
+			g.log.Warn("Can't seek", "frame", cmd.Frame, "err", err)
+		}
+	case render.CmdSetTick:
+		if cmd.Tick < 0 {
+			return nil
+		}
+		// Zero is as fast as possible
+		g.tickDur = cmd.Tick
+		if g.tickDur > 0 {
+			ticker.Reset(g.tickDur)
+		}
+	case render.CmdInspect:
+		g.selected = g.state.entityAt(cmd.Pos)
+	case render.CmdAddFood, render.CmdAddCreech, render.CmdMove, render.CmdDelete:
+		if g.replay != nil {
+			g.log.Warn("Can't edit a replay")
+			return nil
+		}
+		g.handleEdit(cmd)
+	default:
+		return nil
+	}
+	g.updateStats()
+	// Show the effect of the command even when paused
+	return g.show()
+}
+
+// Draw now, or have the sampler draw now if Run has one
+func (g *Game) show() error {
+	if g.sampler == nil {
+		return g.draw()
+	}
+	g.changed = false
+	g.sampler.offer(g.frame())
+	return nil
+}
+
+// Edits happen between ticks, so Update never sees a half-edited State
+func (g *Game) handleEdit(cmd render.Command) {
+	switch cmd.What {
+	case render.CmdAddFood:
+		if !(cmd.Value > 0) {
+			g.log.Warn("Can't add food", "value", cmd.Value)
+			return
+		}
+		f := NewFood(cmd.Value)
+		f.pos = g.wrap(cmd.Pos)
+		g.state.entities.add(f)
+		g.selected = f
+	case render.CmdAddCreech:
+		params, err := DefaultParams().With(cmd.Params)
+		if err != nil {
+			g.log.Warn("Can't add creech", "err", err)
+			return
+		}
+		name := cmd.Name
+		if name == "" {
+			name = "creech"
+		}
+		c := NewCreech(name, g.wrap(cmd.Pos), params)
+		g.state.entities.add(c)
+		g.selected = c
+	case render.CmdMove:
+		e := g.state.entityAt(cmd.Pos)
+		if e == nil {
+			return
+		}
+		err := g.state.moveEntity(e, g.wrap(cmd.To))
+		if err != nil {
+			g.log.Warn("Can't move", "id", e.ID(), "err", err)
+			return
+		}
+		g.selected = e
+	case render.CmdDelete:
+		e := g.state.entityAt(cmd.Pos)
+		if e == nil {
+			return
+		}
+		g.state.entities.Remove(e.ID())
+		if g.selected == e {
+			g.selected = nil
+		}
+	}
+}
+
+func (g *Game) inspect() render.Inspection {
+	state := "running"
+	if g.paused {
+		state = "paused"
+	}
+	info := []render.InfoItem{
+		render.Info("Ticks", "%d", g.ticks),
+		render.Info("State", "%s", state),
+		render.Info("Tick", "%s", g.tickDur),
+	}
+	if g.replay != nil {
+		info = append(info, render.Info("Frames", "%d", len(g.replay.Frames)))
+	}
+	if g.config.Environment.varies() {
+		here := g.EnvironmentAt(Pos{})
+		info = append(info,
+			render.Info("Daylight", "%3.0f%%", here.Daylight*100),
+			render.Info("Food growth", "%4.2f", here.FoodGrowth),
+		)
+	}
+	if g.selected == nil {
+		return render.Inspection{Info: info}
+	}
+
+	info = append(info, render.Info("ID", "%d", g.selected.ID()))
+	switch e := g.selected.(type) {
+	case *Food:
+		info = append(info,
+			render.Info("Kind", "food"),
+			render.Info("Pos", "%s", e.Pos()),
+			render.Info("Terrain", "%s", g.state.terrain.At(e.Pos()).Name),
+			render.Info("Value", "%5.2f", e.value),
+		)
+	case *Creech:
+		plan := e.lastPlan
+		if plan == "" {
+			plan = "-"
+		}
+		info = append(info,
+			render.Info("Kind", "creech"),
+			render.Info("Name", "%s", e.name),
+			render.Info("Species", "%s", e.params.Species),
+			render.Info("Pos", "%s", e.Pos()),
+			render.Info("Terrain", "%s", g.state.terrain.At(e.Pos()).Name),
+			render.Info("Temperature", "%5.1f", g.EnvironmentAt(e.Pos()).Temperature),
+			render.Info("Facing", "%s", e.facing),
+			render.Info("Food", "%5.2f / %5.2f", e.food, e.maxFood()),
+			render.Info("Plan", "%s", plan),
+		)
+		for _, np := range e.params.named() {
+			info = append(info, render.Info(np.Name, "%5.2f", *np.Value))
+		}
+		for _, o := range g.Observe(e.Pos(), e.ViewRegion(), e.ID()) {
+			info = append(info, render.Info("Sees", "%T %d %s", o, o.ID(), o.Pos()))
+		}
+	case *placeholder:
+		info = append(info,
+			render.Info("Kind", "%s", e.kind),
+			render.Info("Pos", "%s", e.Pos()),
+		)
+	default:
+		info = append(info,
+			render.Info("Kind", "%s", entityKind(e)),
+			render.Info("Pos", "%s", e.Pos()),
+		)
+	}
+	selected := g.selected.Pos()
+	return render.Inspection{Info: info, Selected: &selected}
+}
+
+// Update runs each system once
 func (g *Game) Update() {
-	for _, creech := range g.state.creeches {
-		if !creech.Dead() {
-			creech.ModuloPos(g.worldSize)
-			creech.MakePlan(g)
+	for _, sys := range g.systems {
+		sys(g)
+	}
+}
+
+// AddSystem runs sys each tick after those already added, e.g. to
+// update a new kind of entity
+func (g *Game) AddSystem(sys System) {
+	g.systems = append(g.systems, sys)
+}
+
+// Closest entity which covers p, or nil
+func (s *State) entityAt(p Pos) Entity {
+	var found Entity
+	bestDistSq := math.Inf(1)
+	consider := func(e Entity) {
+		// Allow a little slop for small entities
+		r := math.Max(e.Size(), 1)
+		distSq := p.DistanceToSquared(e.Pos())
+		if distSq < r*r && distSq < bestDistSq {
+			found = e
+			bestDistSq = distSq
 		}
 	}
-	for _, creech := range g.state.creeches {
-		creech.DoPlan()
+	for _, e := range s.entities.Entities() {
+		consider(e)
 	}
+	return found
 }
 
-func (g *Game) Observe(r Region, excludeID int64) []Entity {
-	var es []Entity
-	for _, c := range g.state.creeches {
-		if c.ID() != excludeID && r.Contains(c.Pos()) {
-			es = append(es, c)
+func (s *State) moveEntity(e Entity, p Pos) error {
+	pe, ok := e.(placed)
+	if !ok {
+		return fmt.Errorf("Can't move %T, it doesn't embed BaseEntity", e)
+	}
+	pe.setPos(p)
+	return nil
+}
+
+// Observe finds what can be seen from p within r. Obstacles hide
+// things, as do nearer entities in view covering most of them.
+func (g *Game) Observe(p Pos, r Region, excludeID int64) []Entity {
+	inView := Filter(&g.state.entities, func(e Entity) bool {
+		return e.ID() != excludeID && r.Contains(e.Pos())
+	})
+
+	// Nearest first, so only those before an entity can hide it
+	nearest := make([]Entity, len(inView))
+	copy(nearest, inView)
+	dist := make(map[int64]float64, len(inView))
+	for _, e := range inView {
+		dist[e.ID()] = p.DistanceTo(e.Pos())
+	}
+	sort.SliceStable(nearest, func(i, j int) bool {
+		return dist[nearest[i].ID()] < dist[nearest[j].ID()]
+	})
+
+	seen := make(map[int64]bool)
+	for i, e := range nearest {
+		if !g.state.terrain.LineOfSight(p, e.Pos()) {
+			continue
+		}
+		if visibleFraction(p, e, nearest[:i]) >= minVisibleFraction {
+			seen[e.ID()] = true
 		}
 	}
-	for _, f := range g.state.food {
-		if f.ID() != excludeID && r.Contains(f.Pos()) {
-			es = append(es, f)
+
+	var es []Entity
+	for _, e := range inView {
+		if seen[e.ID()] {
+			es = append(es, e)
 		}
 	}
 	return es
@@ -184,21 +759,34 @@ type BaseEntity struct {
 	pos Pos
 }
 
+// The ID is set when the entity is added to a Registry
 func NewBaseEntity(p Pos) BaseEntity {
 	return BaseEntity{
 		pos: p,
-		id:  rand.Int63(),
 	}
 }
 
-func (be *BaseEntity) SetRandomPos(s *State, worldSize Pos, size float64) {
-	be.pos = s.randomEmptyPos(worldSize, size)
+func (be *BaseEntity) SetRandomPos(s *State, worldSize Pos, size float64) error {
+	p, err := s.randomEmptyPos(worldSize, size)
+	if err != nil {
+		return err
+	}
+	be.pos = p
+	return nil
 }
 
 func (be *BaseEntity) ID() int64 {
 	return be.id
 }
 
+func (be *BaseEntity) setID(id int64) {
+	be.id = id
+}
+
+func (be *BaseEntity) setPos(p Pos) {
+	be.pos = p
+}
+
 func (be *BaseEntity) Pos() Pos {
 	return be.pos
 }
@@ -210,9 +798,10 @@ type Plan struct {
 }
 
 func NewPlan(name string, action func()) *Plan {
-	return &Plan{name: name, action: action, cost: 0.1}
+	return &Plan{name: name, action: action}
 }
 
+// Cost is known once the plan has been executed
 func (p *Plan) Cost() float64 {
 	return p.cost
 }
@@ -223,21 +812,23 @@ func (p *Plan) Execute() {
 
 type Creech struct {
 	BaseEntity
-	size float64
+	params Params
 
-	name   string
-	facing Polar
+	name      string
+	facing    Polar
+	viewScale float64 // Night shortens the view
 
-	food float64
-	plan *Plan
+	food     float64
+	plan     *Plan
+	lastPlan string // name of last executed plan, for display
 }
 
-func NewCreech(name string, pos Pos) *Creech {
-	creechSize := 1.0
+func NewCreech(name string, pos Pos, params Params) *Creech {
 	c := &Creech{
 		name:       name,
-		size:       creechSize,
+		params:     params,
 		facing:     North,
+		viewScale:  1,
 		BaseEntity: NewBaseEntity(pos),
 	}
 	c.food = c.maxFood() / 2
@@ -249,7 +840,7 @@ func (c *Creech) Dead() bool {
 }
 
 func (c *Creech) Size() float64 {
-	return c.size
+	return c.params.Size
 }
 
 func (c *Creech) String() string {
@@ -260,65 +851,122 @@ func (c *Creech) Full() bool {
 	return c.food >= c.maxFood()
 }
 
-func (c *Creech) Eat(f *Food) {
+// Eat takes a bite of f, returning how much
+func (c *Creech) Eat(f Edible) float64 {
 	biteSize := c.biteSize()
 	if biteSize > c.maxFood()-c.food {
 		biteSize = c.maxFood() - c.food
 	}
 	f.Consume(biteSize)
 	c.food += biteSize
+	return biteSize
 }
 
 func (c *Creech) MakePlan(g *Game) {
-	region := c.ViewRegion()
-	entities := g.Observe(region, c.ID())
+	c.planFrom(g, c.observe(g))
+}
+
+// What c can see now, published as a SawEvent
+func (c *Creech) observe(g *Game) []Entity {
+	entities := g.Observe(c.Pos(), c.ViewRegion(), c.ID())
+	if tr := g.tracer(c.ID()); tr != nil {
+		tr.Info("Observed", "pos", c.Pos(), "facing", c.facing, "food", c.food, "sees", describeEntities(entities))
+	}
+	if len(entities) > 0 {
+		seen := make([]int64, len(entities))
+		for i, e := range entities {
+			seen[i] = e.ID()
+		}
+		g.events.Publish(SawEvent{g.eventHeader(c.ID()), seen})
+	}
+	return entities
+}
+
+// The built in brain's plan, given what c can see
+func (c *Creech) planFrom(g *Game, entities []Entity) {
+	tr := g.tracer(c.ID())
 	sort.Slice(entities, func(i, j int) bool {
 		return c.Pos().DistanceToSquared(entities[i].Pos()) <
 			c.Pos().DistanceToSquared(entities[j].Pos())
 	})
-	c.plan = c.makeRandomPlan()
+	c.plan = c.makeRandomPlan(&g.state)
+	if tr != nil {
+		tr.Info("Candidate plan", "plan", c.plan.name)
+	}
 	for _, ei := range entities {
 		switch e := ei.(type) {
-		case *Food:
+		case Edible:
 			if c.Full() {
+				if tr != nil {
+					tr.Info("Not hungry", "food", e.ID())
+				}
 				continue
 			}
+			// Plans hold IDs, the target may be gone when they run
+			id := e.ID()
+			if tr != nil {
+				tr.Info("Candidate plan", "plan", "FOOD", "target", id)
+			}
 			c.plan = NewPlan("FOOD", func() {
+				e, ok := Lookup[Edible](&g.state.entities, id)
+				if !ok {
+					return
+				}
 				c.TurnToward(e)
 
 				eatDistance := c.eatDistance(e)
 				if c.Pos().DistanceTo(e.Pos()) < eatDistance {
-					c.Eat(e)
+					bite := c.Eat(e)
+					g.events.Publish(AteEvent{g.eventHeader(c.ID()), id, bite})
 				} else {
-					c.ApproachTo(e, eatDistance)
+					c.ApproachTo(g.state.terrain, e, eatDistance)
 				}
 			})
 			break
-		case *Creech:
+		case Brain:
+			id := e.ID()
+			if tr != nil {
+				tr.Info("Candidate plan", "plan", "FLEE", "target", id)
+			}
 			c.plan = NewPlan("FLEE", func() {
+				e, ok := Lookup[Brain](&g.state.entities, id)
+				if !ok {
+					return
+				}
 				c.TurnAway(e)
-				dist := c.maxMove() * (0.5 + 0.5*rand.Float64())
-				c.pos = c.pos.Move(c.facing.Scale(dist))
+				g.events.Publish(FledEvent{g.eventHeader(c.ID()), id})
+				dist := c.maxMove() * (0.5 + 0.5*g.state.rand.Float64())
+				c.MoveForward(g.state.terrain, dist)
 			})
 			break
-		default:
-			panic(fmt.Sprintf("wtf: %T", ei))
 		}
+		// Anything else, e.g. a rock, is ignored
+	}
+	if tr != nil {
+		tr.Info("Chose plan", "plan", c.plan.name)
 	}
 }
 
-func (c *Creech) DoPlan() {
+// DoPlan spends food on the plan and on keeping warm or cool
+func (c *Creech) DoPlan(e EnergyConfig, t *Terrain, metabolism float64) {
 	if c.plan == nil {
+		c.lastPlan = ""
 		return
 	}
+	c.lastPlan = c.plan.name
+	from, facing := c.pos, c.facing
 	c.plan.Execute()
+	moved := from.DistanceTo(c.pos)
+	turned := math.Abs(AngleDiff(facing.Theta, c.facing.Theta))
+	c.plan.cost = e.Plan + e.Move*moved*t.At(from).MoveCost + e.Turn*turned + metabolism
 	c.food -= c.plan.cost
 	c.plan = nil
 }
 
-func (c *Creech) ApproachTo(e Entity, d float64) {
+func (c *Creech) ApproachTo(t *Terrain, e Entity, d float64) {
 	p := c.Pos().PolarTo(e.Pos())
-	if math.Abs(p.Theta) > math.Pi/2 {
+	if math.Abs(AngleDiff(c.facing.Theta, p.Theta)) > math.Pi/2 {
+		// Behind us
 		return
 	}
 
@@ -326,7 +974,7 @@ func (c *Creech) ApproachTo(e Entity, d float64) {
 	if moveDist > (p.R + d) {
 		moveDist = p.R + d
 	}
-	c.MoveForward(moveDist)
+	c.MoveForward(t, moveDist)
 }
 
 func (c *Creech) TurnAway(e Entity) {
@@ -339,13 +987,15 @@ func (c *Creech) TurnToward(e Entity) {
 	c.facing = c.facing.Turn(dTheta)
 }
 
-func (c *Creech) MoveForward(d float64) {
-	c.pos = c.pos.Move(c.facing.Scale(d))
+// MoveForward goes d at full speed, less on slow ground or at a wall
+func (c *Creech) MoveForward(t *Terrain, d float64) {
+	d *= t.At(c.pos).Speed
+	c.pos = t.Move(c.pos, c.pos.Move(c.facing.Scale(d)))
 }
 
 func turnHelper(facing Polar, p Pos, target Pos, maxTurn float64, towards bool) float64 {
 	joiningLine := p.PolarTo(target)
-	angleToTarget := joiningLine.Theta - facing.Theta
+	angleToTarget := AngleDiff(facing.Theta, joiningLine.Theta)
 	if angleToTarget == 0 {
 		if towards {
 			return 0
@@ -384,70 +1034,85 @@ func turnHelper(facing Polar, p Pos, target Pos, maxTurn float64, towards bool)
 }
 
 func (c *Creech) biteSize() float64 {
-	return 1.5
+	return c.params.BiteSize
 }
 
 func (c *Creech) maxFood() float64 {
-	return 10
+	return c.params.MaxFood
 }
 
 func (c *Creech) maxMove() float64 {
-	return 0.5
+	return c.params.MaxMove
 }
 
 func (c *Creech) maxTurn() float64 {
-	return math.Pi * 0.125
+	return c.params.MaxTurn
 }
 
-func (c *Creech) eatDistance(f *Food) float64 {
+func (c *Creech) eatDistance(f Entity) float64 {
 	return f.Size() + 1
 }
 
 func (c *Creech) viewDistance() float64 {
-	return 10.0
+	return c.params.ViewDistance * c.viewScale
 }
 
 func (c *Creech) viewSideDistance() float64 {
-	return 4.0
+	return c.params.ViewSideDistance * c.viewScale
 }
 
-func (c *Creech) makeRandomPlan() *Plan {
+func (c *Creech) makeRandomPlan(s *State) *Plan {
+	rnd := s.rand
 	return NewPlan("RANDOM", func() {
-		r := rand.Intn(10)
+		r := rnd.Intn(10)
 		if r < 4 {
-			turn := (rand.Float64() - 0.5) * c.maxTurn()
+			turn := (rnd.Float64() - 0.5) * c.maxTurn()
 			c.facing = c.facing.Turn(turn)
 		}
-		dist := c.maxMove() * rand.Float64()
-		c.MoveForward(dist)
+		dist := c.maxMove() * rnd.Float64()
+		c.MoveForward(s.terrain, dist)
 	})
 }
 
-func (c *Creech) ModuloPos(worldSize Pos) {
-	c.pos = moduloPos(c.pos, worldSize)
+// Bring p back into the world
+func (g *Game) wrap(p Pos) Pos {
+	if g.config.World.Topology == TopologyBounded {
+		return clampPos(p, g.worldSize)
+	}
+	return moduloPos(p, g.worldSize)
 }
 
-func moduloPos(p Pos, worldSize Pos) Pos {
-	q := p // Struct copy
-	if q.X > worldSize.X/2 {
-		q.X -= worldSize.X
+func clampPos(p Pos, worldSize Pos) Pos {
+	return Pos{
+		X: math.Max(-worldSize.X/2, math.Min(p.X, worldSize.X/2)),
+		Y: math.Max(-worldSize.Y/2, math.Min(p.Y, worldSize.Y/2)),
 	}
-	if q.X <= -worldSize.X/2 {
-		q.X += worldSize.X
+}
+
+func moduloPos(p Pos, worldSize Pos) Pos {
+	return Pos{X: moduloCoord(p.X, worldSize.X), Y: moduloCoord(p.Y, worldSize.Y)}
+}
+
+// moduloCoord wraps v into (-size/2, size/2]. Non-finite values land on
+// the origin rather than poisoning the position with NaN
+func moduloCoord(v, size float64) float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0
 	}
-	if q.Y > worldSize.Y/2 {
-		q.Y -= worldSize.Y
+	if size <= 0 {
+		return v
 	}
-	if q.Y <= -worldSize.Y/2 {
-		q.Y += worldSize.Y
+	r := math.Mod(v+size/2, size)
+	if r <= 0 {
+		r += size
 	}
-	return q
+	return r - size/2
 }
 
 func (c *Creech) Screen() (int, int, byte) {
 	t := c.facing.Theta
-	i := int(c.pos.X)
-	j := int(c.pos.Y)
+	i := int(math.Floor(c.pos.X))
+	j := int(math.Floor(c.pos.Y))
 	var b byte
 	if c.Dead() {
 		b = 'X'
@@ -465,6 +1130,19 @@ func (c *Creech) Screen() (int, int, byte) {
 	return i, j, b
 }
 
+func (c *Creech) Describe() render.Description {
+	return render.Description{
+		Kind:    "creech",
+		Name:    c.name,
+		Species: c.params.Species,
+		Pos:     c.Pos(),
+		Food:    c.food,
+		Hunger:  1 - c.food/c.maxFood(),
+		Dead:    c.Dead(),
+		Plan:    c.lastPlan,
+	}
+}
+
 func arrow(from, to Pos, headSize float64) []Pos {
 	p := to.Sub(from).Polar()
 	p.R = headSize
@@ -506,20 +1184,20 @@ func (c *Creech) ViewRegion() Region {
 
 func (c *Creech) Web() []render.DrawCommand {
 	if c.Dead() {
-		step := c.facing.Scale(c.size).Pos()
-		sideStep := c.facing.Turn(math.Pi / 2).Scale(c.size).Pos()
+		step := c.facing.Scale(c.params.Size).Pos()
+		sideStep := c.facing.Turn(math.Pi / 2).Scale(c.params.Size).Pos()
 		p := c.Pos()
 		return []render.DrawCommand{
 			render.Poly([]Pos{p.Sub(step), p.Add(step)}),
 			render.Poly([]Pos{p.Sub(sideStep), p.Add(sideStep)}),
 		}
 	}
-	dir := c.facing.Pos().Scale(c.size)
+	dir := c.facing.Pos().Scale(c.params.Size)
 	pts := arrow(c.pos, c.pos.Add(dir), 0.3)
 	region := c.ViewRegion()
 	viewPoly := render.Poly(region.ClosedPoints())
 	viewPoly.DoFill = true
-	colour := render.RGBA{0.5, 0.1, 0.1, 0.2}
+	colour := render.RGBA{R: 0.5, G: 0.1, B: 0.1, A: 0.2}
 	viewPoly.FillColour = colour
 	viewPoly.LineColour = colour
 	return []render.DrawCommand{
@@ -536,7 +1214,7 @@ type Food struct {
 
 func NewFood(value float64) *Food {
 	f := &Food{
-		BaseEntity: NewBaseEntity(Pos{0, 0}),
+		BaseEntity: NewBaseEntity(Pos{X: 0, Y: 0}),
 		value:      value,
 	}
 	return f
@@ -548,13 +1226,17 @@ func (f *Food) String() string {
 
 func (f *Food) Consume(bite float64) {
 	f.value -= bite
-	//	fmt.Printf("Ate %f - %f left\n", bite, f.value)
 }
 
 func (f *Food) Size() float64 {
 	return f.value
 }
 
+// As drawn by Web
+func (f *Food) drawnRadius() float64 {
+	return f.Size() / 2
+}
+
 func (f *Food) Screen() (int, int, byte) {
 	var b byte
 	if f.value < 3 {
@@ -566,11 +1248,19 @@ func (f *Food) Screen() (int, int, byte) {
 	} else {
 		b = '*'
 	}
-	i := int(f.Pos().X)
-	j := int(f.Pos().Y)
+	i := int(math.Floor(f.Pos().X))
+	j := int(math.Floor(f.Pos().Y))
 	return i, j, b
 }
 
+func (f *Food) Describe() render.Description {
+	return render.Description{
+		Kind: "food",
+		Pos:  f.Pos(),
+		Food: f.value,
+	}
+}
+
 func closedPolygon(sides int, p Pos, r float64) []Pos {
 	pts := make([]Pos, sides+1)
 	theta := 0.0
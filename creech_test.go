@@ -10,15 +10,15 @@ import (
 func TestTurnHelper(t *testing.T) {
 	pi2 := math.Pi / 2
 	//	pi4 := math.Pi / 4
-	o := Pos{0, 0}
+	o := Pos{X: 0, Y: 0}
 	mt := math.Pi / 10
 	east := 0.0
 	north := pi2
 	south := -pi2
 	west := math.Pi
 
-	a := Pos{1, 0}
-	b := Pos{1, 1}
+	a := Pos{X: 1, Y: 0}
+	b := Pos{X: 1, Y: 1}
 
 	bigX := 100.0
 	smallAng := math.Atan2(1, bigX)
@@ -33,7 +33,7 @@ func TestTurnHelper(t *testing.T) {
 		expected    float64
 	}{
 		// Copy failing test first
-		{west, o, Pos{bigX, 1}, mt, false, +smallAng},
+		{west, o, Pos{X: bigX, Y: 1}, mt, false, +smallAng},
 		// -----
 
 		{east, o, a, mt, true, 0},
@@ -44,7 +44,7 @@ func TestTurnHelper(t *testing.T) {
 		{north, o, b, mt, true, -mt},
 		{south, o, b, mt, true, +mt},
 
-		{east, o, Pos{bigX, 1}, mt, true, +smallAng},
+		{east, o, Pos{X: bigX, Y: 1}, mt, true, +smallAng},
 
 		{east, o, a, mt, false, +mt},
 		{north, o, a, mt, false, +mt},
@@ -54,7 +54,12 @@ func TestTurnHelper(t *testing.T) {
 		{north, o, b, mt, false, +mt},
 		{south, o, b, mt, false, -mt},
 
-		{west, o, Pos{bigX, 1}, mt, false, +smallAng},
+		{west, o, Pos{X: bigX, Y: 1}, mt, false, +smallAng},
+
+		// Across the join at west, the short way round
+		{west - 0.1, o, Pos{X: -bigX, Y: -1}, mt, true, 0.1 + smallAng},
+		{-west + 0.1, o, Pos{X: -bigX, Y: 1}, mt, true, -0.1 - smallAng},
+		{west - 0.1, o, Pos{X: -bigX, Y: -1}, mt, false, -mt},
 	}
 
 	for _, tc := range testCases {
@@ -70,3 +75,79 @@ func approxEqual(a, b float64) bool {
 	eps := 1e-8
 	return math.Abs(a-b) < eps
 }
+
+func TestEntityAt(t *testing.T) {
+	var s State
+	c := NewCreech("bob", Pos{X: 0, Y: 0}, DefaultParams())
+	f := NewFood(4)
+	f.pos = Pos{X: 10, Y: 10}
+	s.entities.add(c)
+	s.entities.add(f)
+
+	testCases := []struct {
+		p        Pos
+		expected Entity
+	}{
+		{Pos{X: 0, Y: 0}, c},
+		{Pos{X: 0.5, Y: 0.5}, c},
+		{Pos{X: 11, Y: 12}, f},
+		{Pos{X: 5, Y: 5}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("%+v", tc)
+		got := s.entityAt(tc.p)
+		if got != tc.expected {
+			t.Fatalf("got %v expected %v", got, tc.expected)
+		}
+	}
+}
+
+func TestApproachTo(t *testing.T) {
+	testCases := []struct {
+		facing      float64
+		food        Pos
+		expectMoved bool
+	}{
+		{math.Pi, Pos{X: -5, Y: 0}, true},
+		{math.Pi, Pos{X: 5, Y: 0}, false},
+		{-math.Pi / 2, Pos{X: 0.5, Y: -5}, true},
+		{math.Pi / 2, Pos{X: 0.5, Y: -5}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("%+v", tc)
+		c := NewCreech("bob", Pos{X: 0, Y: 0}, DefaultParams())
+		c.facing = Polar{R: 1, Theta: tc.facing}
+		f := NewFood(4)
+		f.pos = tc.food
+		c.ApproachTo(&Terrain{}, f, 0)
+		moved := c.Pos() != Pos{X: 0, Y: 0}
+		if moved != tc.expectMoved {
+			t.Fatalf("got moved %v expected %v", moved, tc.expectMoved)
+		}
+	}
+}
+
+func TestModuloPos(t *testing.T) {
+	world := Pos{X: 100, Y: 50}
+	testCases := []struct {
+		p        Pos
+		expected Pos
+	}{
+		{Pos{X: 0, Y: 0}, Pos{X: 0, Y: 0}},
+		{Pos{X: 50, Y: 25}, Pos{X: 50, Y: 25}},
+		{Pos{X: -50, Y: -25}, Pos{X: 50, Y: 25}},
+		{Pos{X: 60, Y: -30}, Pos{X: -40, Y: 20}},
+		{Pos{X: 1e15 + 10, Y: -1e15 - 10}, Pos{X: 10, Y: -10}},
+		{Pos{X: math.Inf(1), Y: math.Inf(-1)}, Pos{X: 0, Y: 0}},
+		{Pos{X: math.NaN(), Y: 5}, Pos{X: 0, Y: 5}},
+	}
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := moduloPos(tc.p, world)
+		if got != tc.expected {
+			t.Fatalf("Got %v expected %v", got, tc.expected)
+		}
+	}
+}
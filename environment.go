@@ -0,0 +1,156 @@
+package creech
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// EnvironmentConfig drives the fields which vary over the world and
+// over time. The zero lengths turn the cycles off.
+type EnvironmentConfig struct {
+	DayLength  int     // Ticks per day, starting at noon
+	NightView  float64 // View distance is scaled by this at midnight
+	YearLength int     // Ticks per year, starting at midsummer
+
+	// Food regrowth is scaled between 1-SeasonalFood in midwinter and
+	// 1+SeasonalFood in midsummer
+	SeasonalFood float64
+
+	Temperature TemperatureConfig
+}
+
+type TemperatureConfig struct {
+	Mean          float64 // At the origin, averaged over a day and a year
+	Gradient      float64 // Change per metre north, at the origin on a torus
+	DailySwing    float64 // Noon is this much warmer, midnight this much cooler
+	SeasonalSwing float64 // Likewise midsummer and midwinter
+	Comfort       float64 // Creeches spend nothing extra at this temperature
+	Cost          float64 // Food per tick per degree away from Comfort
+}
+
+func (ec *EnvironmentConfig) validate() error {
+	if ec.DayLength < 0 || ec.YearLength < 0 {
+		return fmt.Errorf("day and year lengths must not be negative, got %d and %d", ec.DayLength, ec.YearLength)
+	}
+	if !(ec.NightView > 0 && ec.NightView <= 1) {
+		return fmt.Errorf("NightView must be in (0, 1], got %g", ec.NightView)
+	}
+	if ec.SeasonalFood < 0 || ec.SeasonalFood > 1 {
+		return fmt.Errorf("SeasonalFood must be in [0, 1], got %g", ec.SeasonalFood)
+	}
+	if ec.Temperature.Cost < 0 {
+		return fmt.Errorf("temperature cost must not be negative, got %g", ec.Temperature.Cost)
+	}
+	return nil
+}
+
+// Whether there is anything worth drawing
+func (ec *EnvironmentConfig) varies() bool {
+	t := ec.Temperature
+	return ec.DayLength > 0 || ec.YearLength > 0 || t.Gradient != 0 || t.Cost != 0
+}
+
+// Conditions are the environment at one place and time
+type Conditions struct {
+	Daylight    float64 // 1 at noon, 0 at midnight
+	ViewScale   float64 // Scales view distance
+	FoodGrowth  float64 // Scales food regrowth
+	Temperature float64
+	Metabolism  float64 // Extra food spent per tick
+}
+
+// 1 at the start of each cycle, -1 half way through
+func cycle(ticks, length int) float64 {
+	if length == 0 {
+		return 0
+	}
+	return math.Cos(2 * math.Pi * float64(ticks%length) / float64(length))
+}
+
+// How far north p is for the temperature gradient. On a torus that is
+// a sine with the same slope at the origin, so there is no jump where
+// the north and south edges meet.
+func (w *WorldConfig) northing(p Pos) float64 {
+	if w.Topology != TopologyTorus {
+		return p.Y
+	}
+	return w.Height / (2 * math.Pi) * math.Sin(2*math.Pi*p.Y/w.Height)
+}
+
+func (ec *EnvironmentConfig) at(world *WorldConfig, p Pos, ticks int) Conditions {
+	day := cycle(ticks, ec.DayLength)
+	year := cycle(ticks, ec.YearLength)
+
+	c := Conditions{
+		Daylight:   1,
+		ViewScale:  1,
+		FoodGrowth: 1 + ec.SeasonalFood*year,
+	}
+	if ec.DayLength > 0 {
+		c.Daylight = 0.5 + 0.5*day
+		c.ViewScale = ec.NightView + (1-ec.NightView)*c.Daylight
+	}
+
+	t := ec.Temperature
+	c.Temperature = t.Mean + t.Gradient*world.northing(p) + t.DailySwing*day + t.SeasonalSwing*year
+	c.Metabolism = t.Cost * math.Abs(c.Temperature-t.Comfort)
+	return c
+}
+
+// EnvironmentAt gives the conditions at p now
+func (g *Game) EnvironmentAt(p Pos) Conditions {
+	return g.config.Environment.at(&g.config.World, p, g.ticks)
+}
+
+// Cells across the world in the background layer
+const backgroundCells = 16
+
+// Temperatures this far from comfort get the strongest colour
+const backgroundTempRange = 20.0
+
+// Background shades the world by temperature, darkened at night
+func (g *Game) background() []render.DrawCommand {
+	ec := &g.config.Environment
+	if !ec.varies() {
+		return nil
+	}
+
+	var cmds []render.DrawCommand
+	w, h := g.worldSize.X/backgroundCells, g.worldSize.Y/backgroundCells
+	min := g.worldSize.Scale(-0.5)
+	for j := 0; j < backgroundCells; j++ {
+		for i := 0; i < backgroundCells; i++ {
+			corner := min.Add(Pos{X: float64(i) * w, Y: float64(j) * h})
+			c := ec.at(&g.config.World, corner.Add(Pos{X: w / 2, Y: h / 2}), g.ticks)
+			warmth := (c.Temperature - ec.Temperature.Comfort) / backgroundTempRange
+			warmth = math.Max(-1, math.Min(warmth, 1))
+			colour := render.RGBA{R: 0.5 + 0.5*warmth, G: 0.5, B: 0.5 - 0.5*warmth, A: 0.25}
+			cmds = append(cmds, filledRect(corner, w, h, colour))
+		}
+	}
+
+	night := 1 - ec.at(&g.config.World, Pos{}, g.ticks).Daylight
+	if night > 0 {
+		cmds = append(cmds, filledRect(min, g.worldSize.X, g.worldSize.Y, render.RGBA{R: 0, G: 0, B: 0.1, A: 0.5 * night}))
+	}
+	return cmds
+}
+
+func filledRect(corner Pos, w, h float64, colour render.RGBA) render.DrawCommand {
+	poly := render.Poly([]Pos{
+		corner,
+		corner.Add(Pos{X: w, Y: 0}),
+		corner.Add(Pos{X: w, Y: h}),
+		corner.Add(Pos{X: 0, Y: h}),
+		corner,
+	})
+	poly.DoFill = true
+	poly.FillColour = colour
+	poly.LineColour = colour
+	return poly
+}
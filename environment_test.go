@@ -0,0 +1,72 @@
+package creech
+
+import (
+	"math"
+	"testing"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+func TestEnvironmentAt(t *testing.T) {
+	ec := EnvironmentConfig{
+		DayLength:    100,
+		NightView:    0.5,
+		YearLength:   1000,
+		SeasonalFood: 0.5,
+		Temperature: TemperatureConfig{
+			Mean:          10,
+			Gradient:      -1,
+			DailySwing:    2,
+			SeasonalSwing: 5,
+			Comfort:       15,
+			Cost:          0.1,
+		},
+	}
+
+	bounded := WorldConfig{Width: 40, Height: 40, Topology: TopologyBounded}
+	testCases := []struct {
+		p        Pos
+		ticks    int
+		expected Conditions
+	}{
+		// Noon, midsummer
+		{Pos{X: 0, Y: 0}, 0, Conditions{Daylight: 1, ViewScale: 1, FoodGrowth: 1.5, Temperature: 17, Metabolism: 0.2}},
+		// Further north is colder
+		{Pos{X: 3, Y: 2}, 0, Conditions{Daylight: 1, ViewScale: 1, FoodGrowth: 1.5, Temperature: 15, Metabolism: 0}},
+		// Midnight, still nearly midsummer
+		{Pos{X: 0, Y: 0}, 50, Conditions{Daylight: 0, ViewScale: 0.5, FoodGrowth: 1.4755, Temperature: 12.7553, Metabolism: 0.2245}},
+		// Noon, midwinter
+		{Pos{X: 0, Y: 0}, 500, Conditions{Daylight: 1, ViewScale: 1, FoodGrowth: 0.5, Temperature: 7, Metabolism: 0.8}},
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-4 }
+	for _, tc := range testCases {
+		got := ec.at(&bounded, tc.p, tc.ticks)
+		e := tc.expected
+		if !near(got.Daylight, e.Daylight) || !near(got.ViewScale, e.ViewScale) ||
+			!near(got.FoodGrowth, e.FoodGrowth) || !near(got.Temperature, e.Temperature) ||
+			!near(got.Metabolism, e.Metabolism) {
+			t.Fatalf("At %s tick %d got %+v expected %+v", tc.p, tc.ticks, got, e)
+		}
+	}
+
+	// Turned off, nothing changes
+	off := DefaultConfig().Environment
+	got := off.at(&bounded, Pos{X: 5, Y: 5}, 1234)
+	if got != (Conditions{Daylight: 1, ViewScale: 1, FoodGrowth: 1}) {
+		t.Fatalf("Default environment varies: %+v", got)
+	}
+
+	// On a torus the gradient holds near the origin and wraps smoothly
+	torus := WorldConfig{Width: 40, Height: 40, Topology: TopologyTorus}
+	warm := ec.at(&torus, Pos{X: 0, Y: 0}, 0).Temperature
+	if !near(ec.at(&torus, Pos{X: 0, Y: 0.01}, 0).Temperature, warm-0.01) {
+		t.Fatalf("Got %v expected the gradient at the origin", ec.at(&torus, Pos{X: 0, Y: 0.01}, 0))
+	}
+	south := ec.at(&torus, Pos{X: 0, Y: -20}, 0).Temperature
+	north := ec.at(&torus, Pos{X: 0, Y: 20}, 0).Temperature
+	if !near(south, north) {
+		t.Fatalf("Got %v at the south edge and %v at the north edge", south, north)
+	}
+}
@@ -0,0 +1,126 @@
+package creech
+
+import (
+	"sync"
+	"time"
+)
+
+// A WorldEvent is something which happened in the world, to the
+// entity with the header's ID. Minds and systems may publish their
+// own kinds by embedding EventHeader.
+type WorldEvent interface {
+	Header() EventHeader
+}
+
+type EventHeader struct {
+	Tick int
+	ID   int64
+}
+
+func (h EventHeader) Header() EventHeader {
+	return h
+}
+
+// A creech took a bite of food
+type AteEvent struct {
+	EventHeader
+	FoodID int64
+	Amount float64
+}
+
+// A creech turned away from another
+type FledEvent struct {
+	EventHeader
+	FromID int64
+}
+
+// A creech saw these entities while making its plan
+type SawEvent struct {
+	EventHeader
+	Seen []int64
+}
+
+// A creech arrived after the start, e.g. added by an edit or a scenario
+type BornEvent struct {
+	EventHeader
+}
+
+// A creech starved, or was removed while alive
+type DiedEvent struct {
+	EventHeader
+	Cause string
+}
+
+// A tick has finished, ID is zero
+type StepEvent struct {
+	EventHeader
+	Took time.Duration // Drawing and updating
+}
+
+// Bus passes events to subscribers, in the order they subscribed. It
+// is safe to subscribe and unsubscribe from any goroutine, subscribers
+// are called on the one publishing. The zero value has no subscribers
+// and is ready to use.
+type Bus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   []subscription
+}
+
+type subscription struct {
+	id int
+	f  func(WorldEvent)
+}
+
+// Subscribe calls f with every event published from now on, until
+// unsubscribe is called
+func (b *Bus) Subscribe(f func(WorldEvent)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, subscription{id: id, f: f})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subs {
+			if s.id == id {
+				// Copy, so a Publish in progress is undisturbed
+				subs := make([]subscription, 0, len(b.subs)-1)
+				subs = append(subs, b.subs[:i]...)
+				b.subs = append(subs, b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (b *Bus) Publish(e WorldEvent) {
+	// Not held while calling, so subscribers may subscribe or unsubscribe
+	b.mu.Lock()
+	subs := b.subs
+	b.mu.Unlock()
+	for _, s := range subs {
+		s.f(e)
+	}
+}
+
+// Subscribe calls f with the events on b which are an E
+func Subscribe[E WorldEvent](b *Bus, f func(E)) (unsubscribe func()) {
+	return b.Subscribe(func(e WorldEvent) {
+		if ev, ok := e.(E); ok {
+			f(ev)
+		}
+	})
+}
+
+// Events is the stream of what happens in the world, published as it
+// happens during each tick
+func (g *Game) Events() *Bus {
+	return &g.events
+}
+
+// Header for an event happening to id now
+func (g *Game) eventHeader(id int64) EventHeader {
+	return EventHeader{Tick: g.ticks, ID: id}
+}
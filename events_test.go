@@ -0,0 +1,148 @@
+package creech
+
+import (
+	"testing"
+
+	. "github.com/jbert/creech/pos"
+)
+
+type testEvent struct {
+	EventHeader
+}
+
+func TestBus(t *testing.T) {
+	var b Bus
+	var all, typed []int64
+	unAll := b.Subscribe(func(e WorldEvent) { all = append(all, e.Header().ID) })
+	unTyped := Subscribe(&b, func(e testEvent) { typed = append(typed, e.ID) })
+	// Unsubscribing while publishing still delivers this event
+	var unSelf func()
+	selfCalls := 0
+	unSelf = b.Subscribe(func(WorldEvent) {
+		selfCalls++
+		unSelf()
+	})
+
+	b.Publish(testEvent{EventHeader{ID: 1}})
+	b.Publish(StepEvent{EventHeader: EventHeader{ID: 2}})
+	unTyped()
+	unTyped()
+	b.Publish(testEvent{EventHeader{ID: 3}})
+	unAll()
+	b.Publish(testEvent{EventHeader{ID: 4}})
+
+	if len(all) != 3 || all[2] != 3 {
+		t.Fatalf("Got %v expected [1 2 3]", all)
+	}
+	if len(typed) != 1 || typed[0] != 1 {
+		t.Fatalf("Got %v expected [1]", typed)
+	}
+	if selfCalls != 1 {
+		t.Fatalf("Got %v expected 1 call", selfCalls)
+	}
+}
+
+func TestGameEvents(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Food.Count = 0
+	cfg.Creeches = []PopulationConfig{
+		{Name: "bob", Count: 1, Pos: []Pos{{X: 0, Y: 0}}},
+	}
+	g, err := New(WithConfig(cfg), WithFoodSpawner(FoodSpawnerFunc(func(*Game) {})))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	bob := g.state.creeches()[0]
+	foodID, err := g.AddEntity(NewFoodAt(4, Pos{X: 0, Y: 2}))
+	if err != nil {
+		t.Fatalf("AddEntity: %s", err)
+	}
+
+	counts := make(map[string]int)
+	var ate AteEvent
+	g.Events().Subscribe(func(e WorldEvent) {
+		switch ev := e.(type) {
+		case AteEvent:
+			ate = ev
+			counts["ate"]++
+		case SawEvent:
+			counts["saw"]++
+		case BornEvent:
+			counts["born"]++
+		case DiedEvent:
+			counts[ev.Cause]++
+		case StepEvent:
+			counts["step"]++
+		}
+	})
+
+	err = g.Step()
+	if err != nil {
+		t.Fatalf("Step: %s", err)
+	}
+	if ate.ID != bob.ID() || ate.FoodID != foodID || ate.Tick != 1 || !(ate.Amount > 0) {
+		t.Fatalf("Got %+v expected bob eating food %d at tick 1", ate, foodID)
+	}
+
+	other := NewCreech("other", Pos{X: 20, Y: 20}, DefaultParams())
+	id, err := g.AddEntity(other)
+	if err != nil {
+		t.Fatalf("AddEntity: %s", err)
+	}
+	g.Registry().Remove(id)
+
+	expected := map[string]int{"ate": 1, "saw": 1, "step": 1, "born": 1, DeathRemoved: 1}
+	for k, n := range expected {
+		if counts[k] != n {
+			t.Fatalf("Got %v expected %v", counts, expected)
+		}
+	}
+	if g.life.births != 1 || g.life.deaths[DeathRemoved] != 1 {
+		t.Fatalf("Got %+v expected the life counts to follow the events", g.life)
+	}
+}
+
+// A creech with a Mind sees as one with the built in brain does
+func TestMindSawEvent(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Food.Count = 0
+	cfg.Creeches = []PopulationConfig{
+		{Name: "idle", Species: "idle", Count: 1, Pos: []Pos{{X: 0, Y: 0}}},
+	}
+	idle := MindFunc(func(*Game, *Creech) *Plan { return nil })
+	g, err := New(WithConfig(cfg), WithMind("idle", idle), WithFoodSpawner(FoodSpawnerFunc(func(*Game) {})))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	foodID, err := g.AddEntity(NewFoodAt(4, Pos{X: 0, Y: 2}))
+	if err != nil {
+		t.Fatalf("AddEntity: %s", err)
+	}
+
+	var saw []SawEvent
+	Subscribe(g.Events(), func(e SawEvent) { saw = append(saw, e) })
+	err = g.Step()
+	if err != nil {
+		t.Fatalf("Step: %s", err)
+	}
+	if len(saw) != 1 || len(saw[0].Seen) != 1 || saw[0].Seen[0] != foodID {
+		t.Fatalf("Got %+v expected the food %d seen", saw, foodID)
+	}
+}
+
+// Subscribers may come and go on other goroutines while publishing
+func TestBusConcurrent(t *testing.T) {
+	var b Bus
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			unsubscribe := b.Subscribe(func(WorldEvent) {})
+			unsubscribe()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		b.Publish(testEvent{EventHeader{ID: int64(i)}})
+	}
+	<-done
+}
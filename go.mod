@@ -1,5 +1,5 @@
 module github.com/jbert/creech
 
-go 1.16
+go 1.21
 
-require github.com/gorilla/websocket v1.4.2 // indirect
+require github.com/gorilla/websocket v1.4.2
@@ -0,0 +1,67 @@
+package creech
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// WithLogger logs to l rather than slog.Default()
+func WithLogger(l *slog.Logger) Option {
+	return func(g *Game) error {
+		g.log = l
+		return nil
+	}
+}
+
+// WithTrace logs every decision made by the creech with this ID: what
+// it sees, the plans it weighs up, the one it chooses and its food
+// before and after acting
+func WithTrace(id int64) Option {
+	return func(g *Game) error {
+		g.traceID = id
+		return nil
+	}
+}
+
+// Logger for the entity with this ID if it is traced, or nil
+func (g *Game) tracer(id int64) *slog.Logger {
+	if g.traceID == 0 || id != g.traceID {
+		return nil
+	}
+	return g.log.With("trace", id, "tick", g.ticks)
+}
+
+// Log events for the traced entity, and all of them at debug level
+func (g *Game) logEvents() {
+	debug := g.log.Enabled(context.Background(), slog.LevelDebug)
+	if !debug && g.traceID == 0 {
+		return
+	}
+	g.events.Subscribe(func(e WorldEvent) {
+		h := e.Header()
+		if tr := g.tracer(h.ID); tr != nil {
+			tr.Info("Event", "kind", eventKind(e), "event", e)
+			return
+		}
+		if debug && h.ID != 0 {
+			g.log.Debug("Event", "tick", h.Tick, "id", h.ID, "kind", eventKind(e), "event", e)
+		}
+	})
+}
+
+// AteEvent is "Ate"
+func eventKind(e WorldEvent) string {
+	return strings.TrimSuffix(reflect.TypeOf(e).Name(), "Event")
+}
+
+// Entities as kind, ID and position, for logs
+func describeEntities(es []Entity) []string {
+	ds := make([]string, len(es))
+	for i, e := range es {
+		ds[i] = fmt.Sprintf("%T %d %s", e, e.ID(), e.Pos())
+	}
+	return ds
+}
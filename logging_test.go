@@ -0,0 +1,49 @@
+package creech
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/jbert/creech/pos"
+)
+
+func TestTrace(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Food.Count = 0
+	cfg.Creeches = []PopulationConfig{
+		{Name: "bob", Count: 2, Pos: []Pos{{X: 0, Y: 0}, {X: 15, Y: 15}}},
+	}
+	var out strings.Builder
+	logger := slog.New(slog.NewTextHandler(&out, nil))
+	g, err := New(WithConfig(cfg), WithLogger(logger), WithTrace(1))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	_, err = g.AddEntity(NewFoodAt(4, Pos{X: 0, Y: 2}))
+	if err != nil {
+		t.Fatalf("AddEntity: %s", err)
+	}
+	err = g.Step()
+	if err != nil {
+		t.Fatalf("Step: %s", err)
+	}
+
+	log := out.String()
+	for _, expected := range []string{
+		`msg=Observed trace=1 tick=1`,
+		`msg="Candidate plan" trace=1 tick=1 plan=FOOD target=3`,
+		`msg="Chose plan" trace=1 tick=1 plan=FOOD`,
+		`msg=Acted trace=1 tick=1 plan=FOOD`,
+		`food_before=`,
+		`kind=Ate`,
+	} {
+		t.Logf("TC: %v", expected)
+		if !strings.Contains(log, expected) {
+			t.Fatalf("Got %v expected it to contain %v", log, expected)
+		}
+	}
+	if strings.Contains(log, "trace=2") {
+		t.Fatalf("Got %v expected only creech 1 traced", log)
+	}
+}
@@ -0,0 +1,228 @@
+package creech
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Sample is the set of metrics taken at one tick
+type Sample struct {
+	Tick       int
+	Population map[string]int // Live creeches, by species
+	Births     int            // Since the last sample
+	Deaths     map[string]int // Since the last sample, by cause
+	TotalFood  float64        // Lying in the world
+	MeanFood   float64        // Held by live creeches
+	Params     map[string]Stat
+	Plans      map[string]int // Last executed, by live creeches
+}
+
+type Stat struct {
+	Mean     float64
+	Variance float64
+}
+
+// Causes of death
+const (
+	DeathStarved = "starved"
+	DeathRemoved = "removed"
+)
+
+// Counts of events between samples
+type lifeCounts struct {
+	births int
+	deaths map[string]int
+}
+
+func (lc *lifeCounts) born() {
+	lc.births++
+}
+
+func (lc *lifeCounts) died(cause string) {
+	if lc.deaths == nil {
+		lc.deaths = make(map[string]int)
+	}
+	lc.deaths[cause]++
+}
+
+func (lc *lifeCounts) reset() {
+	*lc = lifeCounts{}
+}
+
+func (g *Game) sample() Sample {
+	s := Sample{
+		Tick:       g.ticks,
+		Population: make(map[string]int),
+		Births:     g.life.births,
+		Deaths:     make(map[string]int),
+		Params:     make(map[string]Stat),
+		Plans:      make(map[string]int),
+	}
+	for cause, n := range g.life.deaths {
+		s.Deaths[cause] = n
+	}
+	for _, f := range g.state.food() {
+		s.TotalFood += f.value
+	}
+
+	var live []*Creech
+	for _, c := range g.state.creeches() {
+		if !c.Dead() {
+			live = append(live, c)
+		}
+	}
+	if len(live) == 0 {
+		return s
+	}
+
+	n := float64(len(live))
+	// Every creech's params are named in the same order
+	var names []string
+	var means []float64
+	for _, c := range live {
+		s.Population[c.params.Species]++
+		s.MeanFood += c.food / n
+		plan := c.lastPlan
+		if plan == "" {
+			plan = "NONE"
+		}
+		s.Plans[plan]++
+		named := c.params.named()
+		if names == nil {
+			names = make([]string, len(named))
+			means = make([]float64, len(named))
+			for i, np := range named {
+				names[i] = np.Name
+			}
+		}
+		for i, np := range named {
+			means[i] += *np.Value
+		}
+	}
+	for i := range means {
+		means[i] /= n
+	}
+	// A second pass about the mean, so a small spread around a large
+	// mean isn't lost to rounding
+	variances := make([]float64, len(names))
+	for _, c := range live {
+		for i, np := range c.params.named() {
+			d := *np.Value - means[i]
+			variances[i] += d * d / n
+		}
+	}
+	for i, name := range names {
+		s.Params[name] = Stat{Mean: means[i], Variance: variances[i]}
+	}
+	return s
+}
+
+// MetricsWriter writes a Sample per tick
+type MetricsWriter interface {
+	Write(s Sample) error
+	Close() error
+}
+
+// NewMetricsWriter picks the format from the extension, ".csv" for
+// CSV or ".jsonl" for JSON lines
+func NewMetricsWriter(path string) (MetricsWriter, error) {
+	ext := filepath.Ext(path)
+	if ext != ".csv" && ext != ".jsonl" {
+		return nil, fmt.Errorf("unknown metrics format %q, want .csv or .jsonl", ext)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't create metrics file: %w", err)
+	}
+	if ext == ".csv" {
+		return newCSVMetrics(f)
+	}
+	return &jsonMetrics{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+type jsonMetrics struct {
+	f   io.WriteCloser
+	enc *json.Encoder
+}
+
+func (jm *jsonMetrics) Write(s Sample) error {
+	return jm.enc.Encode(s)
+}
+
+func (jm *jsonMetrics) Close() error {
+	return jm.f.Close()
+}
+
+// CSV is in long form, one row per metric per tick, since the set
+// of species, causes and plans is not known up front
+type csvMetrics struct {
+	f io.WriteCloser
+	w *csv.Writer
+}
+
+func newCSVMetrics(f io.WriteCloser) (*csvMetrics, error) {
+	cm := &csvMetrics{f: f, w: csv.NewWriter(f)}
+	err := cm.w.Write([]string{"tick", "metric", "value"})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return cm, nil
+}
+
+func (cm *csvMetrics) Write(s Sample) error {
+	tick := strconv.Itoa(s.Tick)
+	row := func(metric string, v float64) error {
+		return cm.w.Write([]string{tick, metric, strconv.FormatFloat(v, 'g', -1, 64)})
+	}
+
+	rows := map[string]float64{
+		"births":     float64(s.Births),
+		"total_food": s.TotalFood,
+		"mean_food":  s.MeanFood,
+	}
+	for species, n := range s.Population {
+		rows["population."+species] = float64(n)
+	}
+	for cause, n := range s.Deaths {
+		rows["deaths."+cause] = float64(n)
+	}
+	for plan, n := range s.Plans {
+		rows["plans."+plan] = float64(n)
+	}
+	for name, stat := range s.Params {
+		rows["params."+name+".mean"] = stat.Mean
+		rows["params."+name+".variance"] = stat.Variance
+	}
+
+	// Stable order, for diffing
+	metrics := make([]string, 0, len(rows))
+	for m := range rows {
+		metrics = append(metrics, m)
+	}
+	sort.Strings(metrics)
+	for _, m := range metrics {
+		err := row(m, rows[m])
+		if err != nil {
+			return err
+		}
+	}
+	cm.w.Flush()
+	return cm.w.Error()
+}
+
+func (cm *csvMetrics) Close() error {
+	cm.w.Flush()
+	err := cm.w.Error()
+	if err != nil {
+		cm.f.Close()
+		return err
+	}
+	return cm.f.Close()
+}
@@ -0,0 +1,125 @@
+package creech
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/jbert/creech/pos"
+)
+
+func TestSample(t *testing.T) {
+	var g Game
+	a := NewCreech("a", Pos{X: 0, Y: 0}, DefaultParams())
+	b := NewCreech("b", Pos{X: 5, Y: 5}, DefaultParams())
+	b.params.MaxMove = 1.5
+	b.food = 3
+	dead := NewCreech("dead", Pos{X: 9, Y: 9}, DefaultParams())
+	dead.food = 0
+	f := NewFood(4)
+	for _, e := range []registered{a, b, dead, f} {
+		g.state.entities.add(e)
+	}
+	g.life.born()
+	g.life.died(DeathStarved)
+
+	s := g.sample()
+	if s.Population["creech"] != 2 {
+		t.Fatalf("got population %v", s.Population)
+	}
+	if s.Births != 1 || s.Deaths[DeathStarved] != 1 {
+		t.Fatalf("got births %d deaths %v", s.Births, s.Deaths)
+	}
+	if !approxEqual(s.TotalFood, 4) || !approxEqual(s.MeanFood, 4) {
+		t.Fatalf("got total food %f mean food %f", s.TotalFood, s.MeanFood)
+	}
+	mm := s.Params["MaxMove"]
+	if !approxEqual(mm.Mean, 1) || !approxEqual(mm.Variance, 0.25) {
+		t.Fatalf("got MaxMove %+v", mm)
+	}
+	// A small spread around a large mean
+	a.params.ViewDistance = 1e9
+	b.params.ViewDistance = 1e9 + 1
+	vd := g.sample().Params["ViewDistance"]
+	if !approxEqual(vd.Mean, 1e9+0.5) || !approxEqual(vd.Variance, 0.25) {
+		t.Fatalf("got ViewDistance %+v", vd)
+	}
+	if s.Plans["NONE"] != 2 {
+		t.Fatalf("got plans %v", s.Plans)
+	}
+}
+
+type nopCloser struct {
+	strings.Builder
+}
+
+func (nc *nopCloser) Close() error {
+	return nil
+}
+
+func TestCSVMetrics(t *testing.T) {
+	var out nopCloser
+	cm, err := newCSVMetrics(&out)
+	if err != nil {
+		t.Fatalf("newCSVMetrics: %s", err)
+	}
+	err = cm.Write(Sample{
+		Tick:       3,
+		Population: map[string]int{"creech": 2},
+		Deaths:     map[string]int{DeathStarved: 1},
+		Params:     map[string]Stat{"Size": {Mean: 1, Variance: 0}},
+	})
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	expected := `tick,metric,value
+3,births,0
+3,deaths.starved,1
+3,mean_food,0
+3,params.Size.mean,1
+3,params.Size.variance,0
+3,population.creech,2
+3,total_food,0
+`
+	if out.String() != expected {
+		t.Fatalf("got:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+// Fails after the first few samples, e.g. on a full disk
+type failingMetrics struct {
+	samples []Sample
+}
+
+func (fm *failingMetrics) Write(s Sample) error {
+	if len(fm.samples) == 2 {
+		return errors.New("disk full")
+	}
+	fm.samples = append(fm.samples, s)
+	return nil
+}
+
+func (fm *failingMetrics) Close() error {
+	return nil
+}
+
+func TestMetricsOnStep(t *testing.T) {
+	fm := &failingMetrics{}
+	g, err := New(WithMetrics(fm))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		err = g.Step()
+		if err != nil {
+			t.Fatalf("Step: %s", err)
+		}
+	}
+	if len(fm.samples) != 2 || fm.samples[1].Tick != 2 {
+		t.Fatalf("Got %v expected a sample after each tick", fm.samples)
+	}
+	err = g.Step()
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Got %v expected the write error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package creech
+
+import (
+	"math"
+	"sort"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// An entity is seen if at least this much of it isn't hidden by nearer ones
+const minVisibleFraction = 0.5
+
+// Entities drawn smaller or larger than their Size say how big they look
+type drawnSizer interface {
+	drawnRadius() float64
+}
+
+func drawnRadius(e Entity) float64 {
+	if ds, ok := e.(drawnSizer); ok {
+		return ds.drawnRadius()
+	}
+	return e.Size()
+}
+
+// Silhouette of e seen from p: a diameter across the line of sight
+func silhouette(p Pos, e Entity) LineSegment {
+	r := math.Max(drawnRadius(e), 0)
+	across := e.Pos().Sub(p).Polar().Turn(math.Pi / 2)
+	across.R = r
+	return NewLineSegment(e.Pos().Sub(across.Pos()), e.Pos().Add(across.Pos()))
+}
+
+// Angles of the ends of ls seen from p, relative to the bearing theta,
+// as lo <= hi. False if ls is behind, so the interval would wrap.
+func angularExtent(p Pos, ls LineSegment, theta float64) (float64, float64, bool) {
+	lo := AngleDiff(theta, p.PolarTo(ls.From).Theta)
+	hi := AngleDiff(theta, p.PolarTo(ls.To).Theta)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi, hi-lo <= math.Pi
+}
+
+// visibleFraction is how much of target, seen from p, is not covered by
+// any occluder nearer to p
+func visibleFraction(p Pos, target Entity, occluders []Entity) float64 {
+	dist := p.DistanceTo(target.Pos())
+	if dist == 0 {
+		return 1
+	}
+	theta := p.PolarTo(target.Pos()).Theta
+	tLo, tHi, _ := angularExtent(p, silhouette(p, target), theta)
+	if tHi-tLo <= 0 {
+		// A point, only hidden if it is covered entirely
+		tLo, tHi = -1e-9, 1e-9
+	}
+
+	type interval struct{ lo, hi float64 }
+	var covered []interval
+	for _, o := range occluders {
+		if o.ID() == target.ID() || p.DistanceTo(o.Pos()) >= dist {
+			continue
+		}
+		lo, hi, ok := angularExtent(p, silhouette(p, o), theta)
+		if !ok {
+			continue
+		}
+		lo, hi = math.Max(lo, tLo), math.Min(hi, tHi)
+		if lo < hi {
+			covered = append(covered, interval{lo, hi})
+		}
+	}
+
+	// Length of the union of the covered intervals
+	sort.Slice(covered, func(i, j int) bool { return covered[i].lo < covered[j].lo })
+	total := 0.0
+	end := tLo
+	for _, iv := range covered {
+		if iv.hi <= end {
+			continue
+		}
+		total += iv.hi - math.Max(iv.lo, end)
+		end = iv.hi
+	}
+	return 1 - total/(tHi-tLo)
+}
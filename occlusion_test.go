@@ -0,0 +1,94 @@
+package creech
+
+import (
+	"math"
+	"testing"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+type testEntity struct {
+	id   int64
+	pos  Pos
+	size float64
+}
+
+func (e testEntity) ID() int64     { return e.id }
+func (e testEntity) Pos() Pos      { return e.pos }
+func (e testEntity) Size() float64 { return e.size }
+
+func TestVisibleFraction(t *testing.T) {
+	target := testEntity{1, Pos{X: 10, Y: 0}, 1}
+
+	testCases := []struct {
+		name      string
+		occluders []Entity
+		expected  float64
+	}{
+		{"nothing", nil, 1},
+		{"big in front", []Entity{testEntity{2, Pos{X: 5, Y: 0}, 2}}, 0},
+		{"behind", []Entity{testEntity{2, Pos{X: 15, Y: 0}, 5}}, 1},
+		{"to the side", []Entity{testEntity{2, Pos{X: 5, Y: 5}, 1}}, 1},
+		{"half covered", []Entity{testEntity{2, Pos{X: 5, Y: 0.5}, 0.5}}, 0.5},
+		{"two halves", []Entity{
+			testEntity{2, Pos{X: 5, Y: 0.5}, 0.5},
+			testEntity{3, Pos{X: 5, Y: -0.5}, 0.5},
+		}, 0},
+		{"overlapping", []Entity{
+			testEntity{2, Pos{X: 5, Y: 0.5}, 0.5},
+			testEntity{3, Pos{X: 6, Y: 0.6}, 0.6},
+		}, 0.5},
+	}
+
+	for _, tc := range testCases {
+		got := visibleFraction(Pos{X: 0, Y: 0}, target, append(tc.occluders, target))
+		// Silhouettes are straight, so allow for the curvature of angles
+		if math.Abs(got-tc.expected) > 0.02 {
+			t.Fatalf("%s: got %f expected %f", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestObserveOcclusion(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Creeches = []PopulationConfig{
+		{Name: "bob", Count: 1, Pos: []Pos{{X: 0, Y: 0}}},
+		{Name: "big", Count: 1, Pos: []Pos{{X: 4, Y: 0}}, Params: map[string]float64{"Size": 3}},
+	}
+	cfg.Food.Count = 0
+	g := NewGame(nil, 0, 1)
+	err := g.SetConfig(cfg)
+	if err != nil {
+		t.Fatalf("SetConfig: %s", err)
+	}
+	err = g.state.AddCreeches(cfg.Creeches, g.worldSize)
+	if err != nil {
+		t.Fatalf("AddCreeches: %s", err)
+	}
+	hidden := NewFood(1)
+	hidden.pos = Pos{X: 8, Y: 0}
+	seen := NewFood(1)
+	seen.pos = Pos{X: 8, Y: 7}
+	g.state.entities.add(hidden)
+	g.state.entities.add(seen)
+
+	bob, big := g.state.creeches()[0], g.state.creeches()[1]
+	view := NewRegion([]Pos{{X: -1, Y: -8}, {X: 10, Y: -8}, {X: 10, Y: 8}, {X: -1, Y: 8}})
+	got := g.Observe(bob.Pos(), view, bob.ID())
+	if len(got) != 2 || got[0] != Entity(big) || got[1] != Entity(seen) {
+		t.Fatalf("Expected bob to see big and one food, saw %v", got)
+	}
+}
+
+func TestFoodOccludesAsDrawn(t *testing.T) {
+	target := testEntity{1, Pos{X: 10, Y: 0}, 1}
+	f := NewFood(2)
+	f.pos = Pos{X: 5, Y: 1.5}
+	// Drawn at radius 1 it only clips the target's edge, at its Size of
+	// 2 it would hide it
+	got := visibleFraction(Pos{X: 0, Y: 0}, target, []Entity{f})
+	if got < minVisibleFraction {
+		t.Fatalf("Got %f expected at least %f", got, minVisibleFraction)
+	}
+}
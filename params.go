@@ -0,0 +1,80 @@
+package creech
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Params are the per-creech constants which govern its body and behaviour
+type Params struct {
+	Species string
+
+	Size             float64
+	BiteSize         float64
+	MaxFood          float64
+	MaxMove          float64
+	MaxTurn          float64
+	ViewDistance     float64
+	ViewSideDistance float64
+}
+
+func DefaultParams() Params {
+	return Params{
+		Species:          "creech",
+		Size:             1.0,
+		BiteSize:         1.5,
+		MaxFood:          10,
+		MaxMove:          0.5,
+		MaxTurn:          math.Pi * 0.125,
+		ViewDistance:     10.0,
+		ViewSideDistance: 4.0,
+	}
+}
+
+type namedParam struct {
+	Name  string
+	Value *float64
+}
+
+// Each numeric parameter by name, in a fixed order
+func (p *Params) named() []namedParam {
+	return []namedParam{
+		{"Size", &p.Size},
+		{"BiteSize", &p.BiteSize},
+		{"MaxFood", &p.MaxFood},
+		{"MaxMove", &p.MaxMove},
+		{"MaxTurn", &p.MaxTurn},
+		{"ViewDistance", &p.ViewDistance},
+		{"ViewSideDistance", &p.ViewSideDistance},
+	}
+}
+
+// With returns a copy of p with the named parameters replaced
+func (p Params) With(overrides map[string]float64) (Params, error) {
+	q := p // Struct copy
+	named := q.named()
+FIELDS:
+	for name, v := range overrides {
+		for _, np := range named {
+			if np.Name == name {
+				*np.Value = v
+				continue FIELDS
+			}
+		}
+		return Params{}, fmt.Errorf("unknown parameter: %s", name)
+	}
+	return q, q.Validate()
+}
+
+func (p Params) Validate() error {
+	if p.Species == "" {
+		return errors.New("species must be set")
+	}
+	for _, np := range p.named() {
+		if !(*np.Value > 0) {
+			return fmt.Errorf("parameter %s must be positive, got %f", np.Name, *np.Value)
+		}
+	}
+	return nil
+}
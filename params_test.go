@@ -0,0 +1,36 @@
+package creech
+
+import "testing"
+
+func TestParamsWith(t *testing.T) {
+	def := DefaultParams()
+
+	testCases := []struct {
+		overrides map[string]float64
+		expectErr bool
+		expected  Params
+	}{
+		{nil, false, def},
+		{map[string]float64{"MaxMove": 0.8}, false, func() Params { p := def; p.MaxMove = 0.8; return p }()},
+		{map[string]float64{"NoSuchParam": 1}, true, Params{}},
+		{map[string]float64{"Size": 0}, true, Params{}},
+		{map[string]float64{"Size": -1}, true, Params{}},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("%+v", tc)
+		got, err := def.With(tc.overrides)
+		if tc.expectErr {
+			if err == nil {
+				t.Fatalf("expected error, got %+v", got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != tc.expected {
+			t.Fatalf("got %+v expected %+v", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package pos
+
+import (
+	"math"
+	"sort"
+)
+
+// Set operations on regions. The results are lists of regions, with
+// the outlines anticlockwise and any holes clockwise, so the winding
+// number of the whole list says what is inside.
+
+func (r Region) Union(s Region) []Region {
+	return combine(r, s, opUnion)
+}
+
+func (r Region) Intersection(s Region) []Region {
+	return combine(r, s, opIntersection)
+}
+
+// Difference is the part of r not in s
+func (r Region) Difference(s Region) []Region {
+	return combine(r, s, opDifference)
+}
+
+type setOp int
+
+const (
+	opUnion setOp = iota
+	opIntersection
+	opDifference
+)
+
+func (r Region) reversed() Region {
+	pts := make([]Pos, len(r.points))
+	for i, p := range r.points {
+		pts[len(pts)-1-i] = p
+	}
+	return NewRegion(pts)
+}
+
+func (r Region) anticlockwise() Region {
+	if r.SignedArea() < 0 {
+		return r.reversed()
+	}
+	return r
+}
+
+// Which side of a region an edge lies
+const (
+	sideOutside = iota
+	sideInside
+	sideSame     // Along an edge going the same way
+	sideOpposite // Along an edge going the other way
+)
+
+// Keep the edges of each region on the right side of the other, then
+// join them up. Taking s away is keeping what is inside the complement
+// of s, which is s turned inside out, and union keeps what is outside.
+func combine(r, s Region, op setOp) []Region {
+	r = r.anticlockwise()
+	s = s.anticlockwise()
+	if op == opDifference {
+		s = s.reversed()
+	}
+	rEdges := splitEdges(r.Edges(), s.Edges())
+	sEdges := splitEdges(s.Edges(), r.Edges())
+
+	keepInside := op != opUnion
+	var kept []LineSegment
+	for _, e := range rEdges {
+		switch side(e, s, sEdges, op == opDifference) {
+		case sideInside:
+			if keepInside {
+				kept = append(kept, e)
+			}
+		case sideOutside:
+			if !keepInside {
+				kept = append(kept, e)
+			}
+		case sideSame:
+			// Keep one copy of the shared edge
+			kept = append(kept, e)
+		}
+	}
+	for _, e := range sEdges {
+		switch side(e, r, rEdges, false) {
+		case sideInside:
+			if keepInside {
+				kept = append(kept, e)
+			}
+		case sideOutside:
+			if !keepInside {
+				kept = append(kept, e)
+			}
+		}
+	}
+	return joinEdges(kept)
+}
+
+// Where e lies relative to the region with the (split) edges given.
+// Inverted regions have their inside and outside swapped.
+func side(e LineSegment, r Region, edges []LineSegment, inverted bool) int {
+	for _, other := range edges {
+		if other.From.Equals(e.From) && other.To.Equals(e.To) {
+			return sideSame
+		}
+		if other.From.Equals(e.To) && other.To.Equals(e.From) {
+			return sideOpposite
+		}
+	}
+	mid := e.From.Add(e.To).Scale(0.5)
+	inside := r.WindingNumber(mid) != 0
+	if inside != inverted {
+		return sideInside
+	}
+	return sideOutside
+}
+
+// Split each edge wherever it meets one of the others
+func splitEdges(edges, others []LineSegment) []LineSegment {
+	var split []LineSegment
+	for _, e := range edges {
+		cuts := []Pos{e.From, e.To}
+		for _, o := range others {
+			// Corners of the other region lying on e, which includes
+			// the ends of any overlap
+			if e.ContainsPos(o.From) {
+				cuts = append(cuts, o.From)
+			}
+			if Orientation(e.From, e.To, o.From) == 0 && Orientation(e.From, e.To, o.To) == 0 {
+				continue
+			}
+			p, ok := e.Intersection(o)
+			if ok {
+				cuts = append(cuts, p)
+			}
+		}
+		sort.Slice(cuts, func(i, j int) bool {
+			return e.From.DistanceToSquared(cuts[i]) < e.From.DistanceToSquared(cuts[j])
+		})
+		last := cuts[0]
+		for _, p := range cuts[1:] {
+			if p.Equals(last) {
+				continue
+			}
+			split = append(split, NewLineSegment(last, p))
+			last = p
+		}
+	}
+	return split
+}
+
+// Join edges end to start into closed regions
+func joinEdges(edges []LineSegment) []Region {
+	used := make([]bool, len(edges))
+	var regions []Region
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		pts := []Pos{edges[start].From}
+		at := edges[start].To
+		closed := false
+		for {
+			if at.Equals(pts[0]) {
+				closed = true
+				break
+			}
+			next := -1
+			for i, e := range edges {
+				if !used[i] && e.From.Equals(at) {
+					next = i
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			pts = append(pts, at)
+			at = edges[next].To
+		}
+		if !closed {
+			continue
+		}
+		pts = dropStraightCorners(pts)
+		if len(pts) >= 3 {
+			r := NewRegion(pts)
+			if math.Abs(r.SignedArea()) > 0 {
+				regions = append(regions, r)
+			}
+		}
+	}
+	return regions
+}
+
+// Remove corners which don't turn, left where edges were split
+func dropStraightCorners(pts []Pos) []Pos {
+	for changed := true; changed && len(pts) >= 3; {
+		changed = false
+		n := len(pts)
+		for i := range pts {
+			prev, next := pts[(i+n-1)%n], pts[(i+1)%n]
+			if Orientation(prev, pts[i], next) == 0 && NewLineSegment(prev, next).ContainsPos(pts[i]) {
+				pts = append(pts[:i:i], pts[i+1:]...)
+				changed = true
+				break
+			}
+		}
+	}
+	return pts
+}
@@ -18,8 +18,43 @@ func (p Pos) Equals(q Pos) bool {
 	return approxEqual(p.X, q.X) && approxEqual(p.Y, q.Y)
 }
 
+// Unit is p scaled to length 1, or the zero vector for the zero vector
 func (p Pos) Unit() Pos {
-	return p.Scale(1 / p.Length())
+	l := p.Length()
+	if l == 0 {
+		return Pos{}
+	}
+	return p.Scale(1 / l)
+}
+
+func (p Pos) Dot(q Pos) float64 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+// Cross is the z part of the 3D cross product, positive if q is
+// anticlockwise from p
+func (p Pos) Cross(q Pos) float64 {
+	return p.X*q.Y - p.Y*q.X
+}
+
+// Lerp goes t of the way from p to q
+func (p Pos) Lerp(q Pos, t float64) Pos {
+	return p.Add(q.Sub(p).Scale(t))
+}
+
+// Rotate turns p anticlockwise about the origin
+func (p Pos) Rotate(theta float64) Pos {
+	sin, cos := math.Sincos(theta)
+	return Pos{p.X*cos - p.Y*sin, p.X*sin + p.Y*cos}
+}
+
+func (p Pos) RotateAbout(about Pos, theta float64) Pos {
+	return about.Add(p.Sub(about).Rotate(theta))
+}
+
+// Perp is p turned a quarter anticlockwise
+func (p Pos) Perp() Pos {
+	return Pos{-p.Y, p.X}
 }
 
 func (p Pos) Polar() Polar {
@@ -93,12 +128,29 @@ func (p Polar) TurnLeft() Polar {
 
 // To -math.Pi < theta <= math.Pi
 func (p Polar) Normalise() Polar {
-	theta := p.Theta
+	return Polar{p.R, NormaliseAngle(p.Theta)}
+}
+
+// NormaliseAngle gives the same angle in (-math.Pi, math.Pi]
+func NormaliseAngle(theta float64) float64 {
+	theta = math.Mod(theta, 2*math.Pi)
 	if theta > math.Pi {
 		theta -= 2 * math.Pi
+	} else if theta <= -math.Pi {
+		theta += 2 * math.Pi
 	}
-	theta = math.Mod(theta, 2*math.Pi)
-	return Polar{p.R, theta}
+	return theta
+}
+
+// AngleDiff is the smallest signed turn from a to b, positive if
+// anticlockwise, in (-math.Pi, math.Pi]
+func AngleDiff(a, b float64) float64 {
+	return NormaliseAngle(b - a)
+}
+
+// LerpAngle turns t of the way from a to b, the short way round
+func LerpAngle(a, b, t float64) float64 {
+	return NormaliseAngle(a + t*AngleDiff(a, b))
 }
 
 func (p Polar) Scale(r float64) Polar {
@@ -0,0 +1,130 @@
+package pos
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+func TestNormaliseAngle(t *testing.T) {
+	testCases := []struct {
+		theta, expected float64
+	}{
+		{0, 0},
+		{math.Pi, math.Pi},
+		{-math.Pi, math.Pi},
+		{3 * math.Pi / 2, -math.Pi / 2},
+		{-3 * math.Pi / 2, math.Pi / 2},
+		{5 * math.Pi, math.Pi},
+		{-5 * math.Pi, math.Pi},
+		{7*math.Pi + 0.5, -math.Pi + 0.5},
+		{-7*math.Pi - 0.5, math.Pi - 0.5},
+		{100, 100 - 32*math.Pi},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := NormaliseAngle(tc.theta)
+		if !approxEqual(got, tc.expected) {
+			t.Fatalf("Got %g expected %g", got, tc.expected)
+		}
+		if p := (Polar{1, tc.theta}).Normalise(); p.Theta != got {
+			t.Fatalf("Polar got %g", p.Theta)
+		}
+	}
+}
+
+func TestQuickNormaliseAngle(t *testing.T) {
+	f := func(theta float64) bool {
+		got := NormaliseAngle(theta)
+		if !(got > -math.Pi && got <= math.Pi) {
+			return false
+		}
+		// Still the same direction
+		a, b := Polar{1, theta}.Pos(), Polar{1, got}.Pos()
+		return a.DistanceTo(b) < 1e-6*math.Max(1, math.Abs(theta))
+	}
+	err := quick.Check(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAngleDiff(t *testing.T) {
+	testCases := []struct {
+		a, b, expected float64
+	}{
+		{0, 1, 1},
+		{1, 0, -1},
+		// The short way round, across the join at Pi
+		{3, -3, 2*math.Pi - 6},
+		{-3, 3, 6 - 2*math.Pi},
+		{0, math.Pi, math.Pi},
+		{math.Pi / 2, -math.Pi / 2, math.Pi},
+		{0, 4 * math.Pi, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := AngleDiff(tc.a, tc.b)
+		if !approxEqual(got, tc.expected) {
+			t.Fatalf("Got %g expected %g", got, tc.expected)
+		}
+	}
+}
+
+func TestLerpAngle(t *testing.T) {
+	got := LerpAngle(3, -3, 0.5)
+	if !approxEqual(math.Abs(got), math.Pi) {
+		t.Fatalf("Got %g expected +/- Pi", got)
+	}
+	got = LerpAngle(-0.5, 0.5, 0.25)
+	if !approxEqual(got, -0.25) {
+		t.Fatalf("Got %g expected -0.25", got)
+	}
+}
+
+func TestVectorOps(t *testing.T) {
+	p := Pos{3, 4}
+	q := Pos{-2, 1}
+
+	if p.Dot(q) != -2 {
+		t.Fatalf("Got dot %g", p.Dot(q))
+	}
+	if p.Cross(q) != 11 || q.Cross(p) != -11 {
+		t.Fatalf("Got cross %g", p.Cross(q))
+	}
+	if !p.Lerp(q, 0.5).Equals(Pos{0.5, 2.5}) {
+		t.Fatalf("Got lerp %s", p.Lerp(q, 0.5))
+	}
+	if !p.Rotate(math.Pi/2).Equals(Pos{-4, 3}) || !p.Rotate(math.Pi/2).Equals(p.Perp()) {
+		t.Fatalf("Got rotate %s", p.Rotate(math.Pi/2))
+	}
+	if !p.RotateAbout(Pos{3, 0}, math.Pi).Equals(Pos{3, -4}) {
+		t.Fatalf("Got rotate about %s", p.RotateAbout(Pos{3, 0}, math.Pi))
+	}
+	if !p.Unit().Equals(Pos{0.6, 0.8}) || (Pos{}).Unit() != (Pos{}) {
+		t.Fatalf("Got unit %s", p.Unit())
+	}
+}
+
+func TestQuickRotate(t *testing.T) {
+	f := func(x, y, theta float64) bool {
+		p := Pos{math.Mod(x, 1e6), math.Mod(y, 1e6)}
+		theta = math.Mod(theta, 100)
+		r := p.Rotate(theta)
+		// Length is kept and the angle turned is theta
+		if math.Abs(r.Length()-p.Length()) > 1e-6*p.Length() {
+			return false
+		}
+		if p.Length() < 1e-6 {
+			return true
+		}
+		turned := AngleDiff(p.Polar().Theta, r.Polar().Theta)
+		return math.Abs(AngleDiff(NormaliseAngle(theta), turned)) < 1e-6
+	}
+	err := quick.Check(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,7 +1,6 @@
 package pos
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -34,95 +33,41 @@ func (r Region) ClosedPoints() []Pos {
 
 type LineSegment struct {
 	From, To Pos
-
-	isVertical     bool
-	gradient, yInt float64
 }
 
 func NewLineSegment(from, to Pos) LineSegment {
-	l := LineSegment{
+	return LineSegment{
 		From: from,
 		To:   to,
 	}
-
-	dY := l.To.Y - l.From.Y
-	dX := l.To.X - l.From.X
-	if approxEqual(dX, 0) {
-		l.isVertical = true
-	}
-	l.gradient = dY / dX
-	l.yInt = l.From.Y - l.gradient*l.From.X
-
-	return l
 }
 
 func (ls LineSegment) String() string {
 	return fmt.Sprintf("%s -> %s", ls.From, ls.To)
 }
 
-var errVertical = errors.New("Line is vertical")
-
-func (ls *LineSegment) GradAndIntercept() (float64, float64, error) {
-	if ls.isVertical {
-		return 0, 0, errVertical
-	}
-	return ls.gradient, ls.yInt, nil
+// Cross product of b-a and c-a, twice the signed area of the triangle
+func cross(a, b, c Pos) float64 {
+	return b.Sub(a).Cross(c.Sub(a))
 }
 
-// Evaluate segment extended to line at X
-func (ls LineSegment) Eval(x float64) (float64, error) {
-	m, c, err := ls.GradAndIntercept()
-	if err != nil {
-		return 0, err
-	}
-	return m*x + c, nil
-}
-
-func (ls LineSegment) LineIntersects(ls2 LineSegment) (Pos, bool) {
-	m, c, err := ls.GradAndIntercept()
-	m2, c2, err2 := ls2.GradAndIntercept()
-	if err != nil && err == err2 {
-		// Both vertical
-		if ls.From.X == ls2.From.X {
-			return Pos{ls.From.X, 0}, true
-		}
-		return Pos{}, false
-	}
+// Relative tolerance below which three points count as collinear
+const collinearEps = 1e-9
 
-	if err2 != nil {
-		// Switch ls and ls2
-		return ls2.LineIntersects(ls)
+// Orientation of c relative to the line a -> b: 1 if c is to the left
+// (anticlockwise), -1 if to the right, 0 if collinear. The tolerance
+// scales with the lengths involved, so it holds far from the origin.
+func Orientation(a, b, c Pos) int {
+	cr := cross(a, b, c)
+	tol := collinearEps * b.Sub(a).Length() * c.Sub(a).Length()
+	switch {
+	case cr > tol:
+		return 1
+	case cr < -tol:
+		return -1
+	default:
+		return 0
 	}
-
-	if err != nil {
-		// Handle ls vertical, ls2 not
-		x := ls.From.X
-		var y float64
-		y, err = ls2.Eval(x)
-		if err != nil {
-			panic("Expected ls2 to be non-vertical")
-		}
-		return Pos{x, y}, true
-	}
-
-	// Neither vertical
-
-	if m == m2 {
-		// Parallel
-		if c != c2 {
-			// not co-incident
-			return Pos{}, false
-		}
-		// Co-incident
-		return Pos{0, c}, true
-	}
-
-	x := (c2 - c) / (m - m2)
-	y, err := ls.Eval(x)
-	if err != nil {
-		panic("Expected ls2 to be non-vertial")
-	}
-	return Pos{x, y}, true
 }
 
 func (ls LineSegment) BoundingRectContains(p Pos) bool {
@@ -141,27 +86,91 @@ func (ls LineSegment) ContainsSeg(ls2 LineSegment) bool {
 }
 
 func (ls LineSegment) ContainsPos(p Pos) bool {
-	if !ls.BoundingRectContains(p) {
-		return false
+	if ls.From == ls.To {
+		return p == ls.From
 	}
-	if ls.isVertical {
-		return approxEqual(p.X, ls.From.X)
+	return Orientation(ls.From, ls.To, p) == 0 && ls.BoundingRectContains(p)
+}
+
+// DistanceTo is the distance from p to the nearest point on ls
+func (ls LineSegment) DistanceTo(p Pos) float64 {
+	d := ls.To.Sub(ls.From)
+	lenSq := d.Dot(d)
+	if lenSq == 0 {
+		return p.DistanceTo(ls.From)
 	}
-	y, err := ls.Eval(p.X)
-	if err != nil {
-		panic("ls should not be vertical")
+	t := p.Sub(ls.From).Dot(d) / lenSq
+	t = math.Max(0, math.Min(t, 1))
+	return p.DistanceTo(ls.From.Lerp(ls.To, t))
+}
+
+// LineIntersects finds where the lines through ls and ls2 meet. False
+// if they are parallel and distinct, ls.From if they coincide.
+func (ls LineSegment) LineIntersects(ls2 LineSegment) (Pos, bool) {
+	r := ls.To.Sub(ls.From)
+	s := ls2.To.Sub(ls2.From)
+	denom := r.Cross(s)
+	if Orientation(Pos{}, r, s) == 0 || denom == 0 {
+		// Parallel
+		if Orientation(ls2.From, ls2.To, ls.From) == 0 {
+			return ls.From, true
+		}
+		return Pos{}, false
 	}
-	return approxEqual(y, p.Y)
+	t := cross(ls.From, ls2.From, ls2.To) / denom
+	return ls.From.Add(r.Scale(t)), true
 }
 
+// SegmentIntersectsLine is true if the segments ls and ray share a
+// point, including touching at an end or overlapping along a line
 func (ls LineSegment) SegmentIntersectsLine(ray LineSegment) bool {
-	p, intersect := ls.LineIntersects(ray)
-	if !intersect {
-		return false
+	_, ok := ls.Intersection(ray)
+	return ok
+}
+
+// Intersection finds the point shared by the segments ls and ls2
+// nearest to ls.From
+func (ls LineSegment) Intersection(ls2 LineSegment) (Pos, bool) {
+	o1 := Orientation(ls.From, ls.To, ls2.From)
+	o2 := Orientation(ls.From, ls.To, ls2.To)
+	o3 := Orientation(ls2.From, ls2.To, ls.From)
+	o4 := Orientation(ls2.From, ls2.To, ls.To)
+
+	if o1 != 0 && o1 == o2 || o3 != 0 && o3 == o4 {
+		// Both ends of one lie strictly on the same side of the other
+		return Pos{}, false
 	}
-	ok1 := ls.BoundingRectContains(p)
-	ok2 := ray.BoundingRectContains(p)
-	return ok1 && ok2
+	if o1 != o2 && o3 != o4 {
+		// A proper crossing, or an end of one lying on the other
+		if o3 == 0 {
+			return ls.From, true
+		}
+		if o1 == 0 {
+			return ls2.From, true
+		}
+		if o2 == 0 {
+			return ls2.To, true
+		}
+		if o4 == 0 {
+			return ls.To, true
+		}
+		p, _ := ls.LineIntersects(ls2)
+		return p, true
+	}
+
+	// Collinear, or degenerate: find the shared point nearest ls.From
+	var best Pos
+	bestDistSq := math.Inf(1)
+	for _, p := range []Pos{ls.From, ls.To, ls2.From, ls2.To} {
+		if !ls.ContainsPos(p) || !ls2.ContainsPos(p) {
+			continue
+		}
+		distSq := ls.From.DistanceToSquared(p)
+		if distSq < bestDistSq {
+			best, bestDistSq = p, distSq
+		}
+	}
+	return best, !math.IsInf(bestDistSq, 1)
 }
 
 func (r Region) Translate(v Pos) Region {
@@ -172,37 +181,173 @@ func (r Region) Translate(v Pos) Region {
 	return NewRegion(pts)
 }
 
-func (r Region) Overlaps(s Region) bool {
-	for _, p := range s.ClosedPoints() {
-		if r.Contains(p) {
-			return true
+// Overlaps is true if r and s share any point, including where only
+// their edges cross
+func (r Region) Overlaps(s Shape) bool {
+	return Overlaps(r, s)
+}
+
+func (r Region) Rotate(about Pos, theta float64) Region {
+	pts := make([]Pos, len(r.points))
+	for i := range r.points {
+		pts[i] = r.points[i].RotateAbout(about, theta)
+	}
+	return NewRegion(pts)
+}
+
+// Points gives the corners in order, without repeating the first
+func (r Region) Points() []Pos {
+	pts := make([]Pos, len(r.points))
+	copy(pts, r.points)
+	return pts
+}
+
+// SignedArea is positive if the points go anticlockwise
+func (r Region) SignedArea() float64 {
+	total := 0.0
+	for _, edge := range r.Edges() {
+		total += edge.From.Cross(edge.To)
+	}
+	return total / 2
+}
+
+func (r Region) Area() float64 {
+	return math.Abs(r.SignedArea())
+}
+
+func (r Region) Centroid() Pos {
+	a := r.SignedArea()
+	if a == 0 {
+		// Degenerate, use the mean of the points
+		var sum Pos
+		for _, p := range r.points {
+			sum = sum.Add(p)
+		}
+		return sum.Scale(1 / float64(len(r.points)))
+	}
+	var c Pos
+	for _, edge := range r.Edges() {
+		w := edge.From.Cross(edge.To)
+		c = c.Add(edge.From.Add(edge.To).Scale(w))
+	}
+	return c.Scale(1 / (6 * a))
+}
+
+func (r Region) BoundingBox() Rect {
+	return boundingBox(r.points)
+}
+
+// IsConvex is true if every corner turns the same way. Points in a
+// straight line have no area so aren't convex.
+func (r Region) IsConvex() bool {
+	n := len(r.points)
+	if n < 3 || r.SignedArea() == 0 {
+		return false
+	}
+	sign := 0
+	for i := range r.points {
+		o := Orientation(r.points[i], r.points[(i+1)%n], r.points[(i+2)%n])
+		if o == 0 {
+			continue
+		}
+		if sign != 0 && o != sign {
+			return false
+		}
+		sign = o
+	}
+	// A star goes round more than once while turning one way
+	return r.simple()
+}
+
+// Whether no two edges cross or touch, other than neighbours at
+// their shared corner
+func (r Region) simple() bool {
+	edges := r.Edges()
+	n := len(edges)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			if edges[i].SegmentIntersectsLine(edges[j]) {
+				return false
+			}
 		}
 	}
-	return false
+	return true
 }
 
+func (r Region) boundary() []boundaryPiece {
+	edges := r.Edges()
+	pieces := make([]boundaryPiece, len(edges))
+	for i := range edges {
+		pieces[i] = boundaryPiece{seg: &edges[i]}
+	}
+	return pieces
+}
+
+func (r Region) anyPoint() Pos {
+	return r.points[0]
+}
+
+// Contains is true for points inside r or on its edge. Self-intersecting
+// regions use the non-zero winding rule.
 func (r Region) Contains(q Pos) bool {
-	// Take a line segment "to infinity"
-	big := 1e7
-	qRay := NewLineSegment(q, Pos{0, big})
-
-	var last Pos
-	numIntersects := 0
-	for i, p := range r.ClosedPoints() {
-		if i > 0 {
-			seg := NewLineSegment(last, p)
-			if seg.ContainsPos(q) {
-				return true
+	for _, edge := range r.Edges() {
+		if edge.ContainsPos(q) {
+			return true
+		}
+	}
+	return r.WindingNumber(q) != 0
+}
+
+// WindingNumber counts how many times the edges of r go anticlockwise
+// around q, which must not lie on an edge. Each edge is counted where
+// it crosses the horizontal through q, upward edges including their
+// start and downward ones their end, so vertices level with q count
+// once.
+func (r Region) WindingNumber(q Pos) int {
+	wn := 0
+	for _, edge := range r.Edges() {
+		a, b := edge.From, edge.To
+		if a.Y <= q.Y {
+			if b.Y > q.Y && Orientation(a, b, q) > 0 {
+				wn++
 			}
-			// If collinear with one side, but the pt is not within the side
-			// Do not count this tangential ray
-			if !qRay.ContainsSeg(seg) {
-				if seg.SegmentIntersectsLine(qRay) {
-					numIntersects++
-				}
+		} else {
+			if b.Y <= q.Y && Orientation(a, b, q) < 0 {
+				wn--
 			}
 		}
-		last = p
 	}
-	return numIntersects%2 == 1
+	return wn
+}
+
+// Edges of the region, closing back to the first point
+func (r Region) Edges() []LineSegment {
+	pts := r.ClosedPoints()
+	edges := make([]LineSegment, len(pts)-1)
+	for i := range edges {
+		edges[i] = NewLineSegment(pts[i], pts[i+1])
+	}
+	return edges
+}
+
+// FirstCrossing finds where ls first crosses the edge of the region,
+// going from ls.From, and the edge it crosses
+func (r Region) FirstCrossing(ls LineSegment) (Pos, LineSegment, bool) {
+	var best Pos
+	var bestEdge LineSegment
+	bestDistSq := math.Inf(1)
+	for _, edge := range r.Edges() {
+		p, ok := ls.Intersection(edge)
+		if !ok {
+			continue
+		}
+		distSq := ls.From.DistanceToSquared(p)
+		if distSq < bestDistSq {
+			best, bestEdge, bestDistSq = p, edge, distSq
+		}
+	}
+	return best, bestEdge, !math.IsInf(bestDistSq, 1)
 }
@@ -0,0 +1,123 @@
+package pos
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// Reference winding number, by adding up the angles the edges subtend
+func angleWindingNumber(r Region, q Pos) int {
+	total := 0.0
+	for _, edge := range r.Edges() {
+		a := q.PolarTo(edge.From).Theta
+		b := q.PolarTo(edge.To).Theta
+		d := b - a
+		for d > math.Pi {
+			d -= 2 * math.Pi
+		}
+		for d <= -math.Pi {
+			d += 2 * math.Pi
+		}
+		total += d
+	}
+	return int(math.Round(total / (2 * math.Pi)))
+}
+
+func nearEdge(r Region, q Pos, dist float64) bool {
+	for _, edge := range r.Edges() {
+		if edge.DistanceTo(q) < dist {
+			return true
+		}
+	}
+	return false
+}
+
+// Random, possibly self-intersecting, polygons on a coarse grid, so
+// vertices often line up with each other and with the test point
+type quickRegion struct {
+	Region
+	Q Pos
+}
+
+func (quickRegion) Generate(rnd *rand.Rand, size int) reflect.Value {
+	coord := func() float64 { return float64(rnd.Intn(9) - 4) }
+	n := 3 + rnd.Intn(6)
+	pts := make([]Pos, n)
+	for i := range pts {
+		pts[i] = Pos{coord(), coord()}
+	}
+	q := Pos{coord(), coord()}
+	if rnd.Intn(2) == 0 {
+		q = q.Add(Pos{rnd.Float64(), rnd.Float64()})
+	}
+	return reflect.ValueOf(quickRegion{NewRegion(pts), q})
+}
+
+func TestQuickWindingNumber(t *testing.T) {
+	f := func(qr quickRegion) bool {
+		if nearEdge(qr.Region, qr.Q, 1e-6) {
+			return qr.Contains(qr.Q)
+		}
+		wn := qr.WindingNumber(qr.Q)
+		if wn != angleWindingNumber(qr.Region, qr.Q) {
+			t.Logf("%s around %s: got %d", qr.Region, qr.Q, wn)
+			return false
+		}
+		return qr.Contains(qr.Q) == (wn != 0)
+	}
+	err := quick.Check(f, &quick.Config{MaxCount: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuickContainsTranslated(t *testing.T) {
+	f := func(qr quickRegion, dx, dy int32) bool {
+		v := Pos{float64(dx), float64(dy)}.Scale(10)
+		return qr.Contains(qr.Q) == qr.Translate(v).Contains(qr.Q.Add(v))
+	}
+	err := quick.Check(f, &quick.Config{MaxCount: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type quickSegments struct {
+	A, B LineSegment
+}
+
+func (quickSegments) Generate(rnd *rand.Rand, size int) reflect.Value {
+	coord := func() float64 { return float64(rnd.Intn(7) - 3) }
+	seg := func() LineSegment {
+		return NewLineSegment(Pos{coord(), coord()}, Pos{coord(), coord()})
+	}
+	return reflect.ValueOf(quickSegments{seg(), seg()})
+}
+
+func TestQuickIntersection(t *testing.T) {
+	f := func(qs quickSegments) bool {
+		p, ok := qs.A.Intersection(qs.B)
+		_, okBA := qs.B.Intersection(qs.A)
+		if ok != okBA {
+			return false
+		}
+		if !ok {
+			// Then no point along A lies on B
+			for i := 0; i <= 16; i++ {
+				along := qs.A.From.Add(qs.A.To.Sub(qs.A.From).Scale(float64(i) / 16))
+				if qs.B.DistanceTo(along) < 1e-9 {
+					return false
+				}
+			}
+			return true
+		}
+		return qs.A.DistanceTo(p) < 1e-9 && qs.B.DistanceTo(p) < 1e-9
+	}
+	err := quick.Check(f, &quick.Config{MaxCount: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -175,3 +175,126 @@ func TestRegionContains(t *testing.T) {
 		}
 	}
 }
+
+func TestRegionFirstCrossing(t *testing.T) {
+	unitSquare := NewRegion(
+		[]Pos{
+			Pos{0, 0},
+			Pos{1, 0},
+			Pos{1, 1},
+			Pos{0, 1},
+		},
+	)
+
+	testCases := []struct {
+		from, to     Pos
+		expected     bool
+		expectedPos  Pos
+		expectedEdge LineSegment
+	}{
+		{Pos{-1, 0.5}, Pos{2, 0.5}, true, Pos{0, 0.5}, NewLineSegment(Pos{0, 1}, Pos{0, 0})},
+		{Pos{2, 0.5}, Pos{-1, 0.5}, true, Pos{1, 0.5}, NewLineSegment(Pos{1, 0}, Pos{1, 1})},
+		{Pos{0.5, 2}, Pos{0.5, 0.5}, true, Pos{0.5, 1}, NewLineSegment(Pos{1, 1}, Pos{0, 1})},
+		{Pos{-1, 0.5}, Pos{-0.5, 0.5}, false, Pos{}, LineSegment{}},
+		{Pos{2, 2}, Pos{3, 1}, false, Pos{}, LineSegment{}},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		p, edge, got := unitSquare.FirstCrossing(NewLineSegment(tc.from, tc.to))
+		if got != tc.expected {
+			t.Fatalf("Got %v expected %v", got, tc.expected)
+		}
+		if !got {
+			continue
+		}
+		if !p.Equals(tc.expectedPos) {
+			t.Fatalf("Got crossing at %s expected %s", p, tc.expectedPos)
+		}
+		if !edge.From.Equals(tc.expectedEdge.From) || !edge.To.Equals(tc.expectedEdge.To) {
+			t.Fatalf("Got edge %s expected %s", edge, tc.expectedEdge)
+		}
+	}
+}
+
+func TestRegionContainsDegenerate(t *testing.T) {
+	diamond := NewRegion([]Pos{{0, -1}, {1, 0}, {0, 1}, {-1, 0}})
+	far := diamond.Translate(Pos{3e7, -5e8})
+
+	testCases := []struct {
+		region   Region
+		p        Pos
+		expected bool
+	}{
+		// Level with the left and right vertices
+		{diamond, Pos{-2, 0}, false},
+		{diamond, Pos{0, 0}, true},
+		{diamond, Pos{2, 0}, false},
+		// Straight above and below the top and bottom vertices
+		{diamond, Pos{0, 2}, false},
+		{diamond, Pos{0, -2}, false},
+		// Beyond the old ray's end
+		{far, Pos{3e7, -5e8}, true},
+		{far, Pos{3e7 + 0.9, -5e8}, true},
+		{far, Pos{3e7 + 1.1, -5e8}, false},
+		{far, Pos{3e7 - 2, -5e8}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := tc.region.Contains(tc.p)
+		if got != tc.expected {
+			t.Fatalf("Got %v expected %v", got, tc.expected)
+		}
+	}
+}
+
+func TestSegmentIntersection(t *testing.T) {
+	testCases := []struct {
+		a, b        LineSegment
+		expected    bool
+		expectedPos Pos
+	}{
+		{NewLineSegment(Pos{0, 0}, Pos{2, 2}), NewLineSegment(Pos{0, 2}, Pos{2, 0}), true, Pos{1, 1}},
+		// Vertical and horizontal
+		{NewLineSegment(Pos{1, -1}, Pos{1, 1}), NewLineSegment(Pos{0, 0}, Pos{2, 0}), true, Pos{1, 0}},
+		// Lines cross, segments don't
+		{NewLineSegment(Pos{0, 0}, Pos{1, 1}), NewLineSegment(Pos{3, 0}, Pos{2, 1}), false, Pos{}},
+		// Touching at an end
+		{NewLineSegment(Pos{0, 0}, Pos{1, 0}), NewLineSegment(Pos{1, 0}, Pos{1, 5}), true, Pos{1, 0}},
+		// Parallel
+		{NewLineSegment(Pos{0, 0}, Pos{1, 0}), NewLineSegment(Pos{0, 1}, Pos{1, 1}), false, Pos{}},
+		// Collinear, overlapping and apart
+		{NewLineSegment(Pos{0, 0}, Pos{4, 0}), NewLineSegment(Pos{6, 0}, Pos{2, 0}), true, Pos{2, 0}},
+		{NewLineSegment(Pos{4, 0}, Pos{0, 0}), NewLineSegment(Pos{6, 0}, Pos{2, 0}), true, Pos{4, 0}},
+		{NewLineSegment(Pos{0, 0}, Pos{1, 0}), NewLineSegment(Pos{2, 0}, Pos{3, 0}), false, Pos{}},
+		// Collinear and vertical
+		{NewLineSegment(Pos{0, 0}, Pos{0, 3}), NewLineSegment(Pos{0, 1}, Pos{0, 2}), true, Pos{0, 1}},
+		// A point
+		{NewLineSegment(Pos{1, 1}, Pos{1, 1}), NewLineSegment(Pos{0, 0}, Pos{2, 2}), true, Pos{1, 1}},
+		{NewLineSegment(Pos{1, 2}, Pos{1, 2}), NewLineSegment(Pos{0, 0}, Pos{2, 2}), false, Pos{}},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		p, got := tc.a.Intersection(tc.b)
+		if got != tc.expected {
+			t.Fatalf("Got %v expected %v", got, tc.expected)
+		}
+		if got && !p.Equals(tc.expectedPos) {
+			t.Fatalf("Got intersection at %s expected %s", p, tc.expectedPos)
+		}
+		if tc.b.SegmentIntersectsLine(tc.a) != got {
+			t.Fatalf("Not symmetric")
+		}
+	}
+}
+
+func TestLineIntersectsCoincident(t *testing.T) {
+	a := NewLineSegment(Pos{1, 1}, Pos{2, 2})
+	b := NewLineSegment(Pos{5, 5}, Pos{7, 7})
+	p, ok := a.LineIntersects(b)
+	if !ok || !a.ContainsPos(p) || !b.ContainsPos(p.Add(Pos{4, 4})) {
+		t.Fatalf("Got %s %v, expected a point on both lines", p, ok)
+	}
+}
@@ -0,0 +1,379 @@
+package pos
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Shape is a closed area of the plane, boundary included
+type Shape interface {
+	Contains(p Pos) bool
+	Area() float64
+	Centroid() Pos
+	BoundingBox() Rect
+
+	// Pieces of the boundary, and a point in the shape, for overlap tests
+	boundary() []boundaryPiece
+	anyPoint() Pos
+}
+
+// Rect is an axis-aligned box
+type Rect struct {
+	Min, Max Pos
+}
+
+func (r Rect) String() string {
+	return fmt.Sprintf("%s - %s", r.Min, r.Max)
+}
+
+func (r Rect) Contains(p Pos) bool {
+	return r.Min.X <= p.X && p.X <= r.Max.X && r.Min.Y <= p.Y && p.Y <= r.Max.Y
+}
+
+func (r Rect) Overlaps(s Rect) bool {
+	return r.Min.X <= s.Max.X && s.Min.X <= r.Max.X && r.Min.Y <= s.Max.Y && s.Min.Y <= r.Max.Y
+}
+
+// Extend grows r to cover p
+func (r Rect) Extend(p Pos) Rect {
+	return Rect{
+		Min: Pos{math.Min(r.Min.X, p.X), math.Min(r.Min.Y, p.Y)},
+		Max: Pos{math.Max(r.Max.X, p.X), math.Max(r.Max.Y, p.Y)},
+	}
+}
+
+func boundingBox(pts []Pos) Rect {
+	r := Rect{pts[0], pts[0]}
+	for _, p := range pts[1:] {
+		r = r.Extend(p)
+	}
+	return r
+}
+
+type Circle struct {
+	Centre Pos
+	R      float64
+}
+
+func NewCircle(centre Pos, r float64) Circle {
+	return Circle{Centre: centre, R: r}
+}
+
+func (c Circle) String() string {
+	return fmt.Sprintf("circle %s r %0.5f", c.Centre, c.R)
+}
+
+func (c Circle) Contains(p Pos) bool {
+	return c.Centre.DistanceToSquared(p) <= c.R*c.R
+}
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.R * c.R
+}
+
+func (c Circle) Centroid() Pos {
+	return c.Centre
+}
+
+func (c Circle) BoundingBox() Rect {
+	d := Pos{c.R, c.R}
+	return Rect{c.Centre.Sub(d), c.Centre.Add(d)}
+}
+
+func (c Circle) Translate(v Pos) Circle {
+	return Circle{c.Centre.Add(v), c.R}
+}
+
+func (c Circle) Rotate(about Pos, theta float64) Circle {
+	return Circle{c.Centre.RotateAbout(about, theta), c.R}
+}
+
+func (c Circle) boundary() []boundaryPiece {
+	return []boundaryPiece{{arc: &arc{centre: c.Centre, r: c.R, from: 0, sweep: 2 * math.Pi}}}
+}
+
+func (c Circle) anyPoint() Pos {
+	return c.Centre
+}
+
+// Sector is the part of a circle within HalfAngle of Facing, a cone
+// of sight. HalfAngle is at most math.Pi, which is the whole circle.
+type Sector struct {
+	Centre    Pos
+	R         float64
+	Facing    float64
+	HalfAngle float64
+}
+
+func NewSector(centre Pos, r, facing, halfAngle float64) Sector {
+	return Sector{Centre: centre, R: r, Facing: facing, HalfAngle: halfAngle}
+}
+
+func (s Sector) String() string {
+	return fmt.Sprintf("sector %s r %0.5f facing %0.5f +/- %0.5f", s.Centre, s.R, s.Facing, s.HalfAngle)
+}
+
+// Whether the bearing theta lies within the sector's angle
+func (s Sector) within(theta float64) bool {
+	return math.Abs(AngleDiff(s.Facing, theta)) <= s.HalfAngle
+}
+
+func (s Sector) Contains(p Pos) bool {
+	if s.Centre.DistanceToSquared(p) > s.R*s.R {
+		return false
+	}
+	if p == s.Centre {
+		return true
+	}
+	return s.within(s.Centre.PolarTo(p).Theta)
+}
+
+func (s Sector) Area() float64 {
+	return s.R * s.R * s.HalfAngle
+}
+
+func (s Sector) Centroid() Pos {
+	if s.HalfAngle == 0 {
+		return s.Centre.Move(Polar{s.R / 2, s.Facing})
+	}
+	d := 2 * s.R * math.Sin(s.HalfAngle) / (3 * s.HalfAngle)
+	return s.Centre.Move(Polar{d, s.Facing})
+}
+
+// Ends of the arc, anticlockwise
+func (s Sector) arcEnds() (Pos, Pos) {
+	return s.Centre.Move(Polar{s.R, s.Facing - s.HalfAngle}),
+		s.Centre.Move(Polar{s.R, s.Facing + s.HalfAngle})
+}
+
+func (s Sector) BoundingBox() Rect {
+	from, to := s.arcEnds()
+	r := boundingBox([]Pos{s.Centre, from, to})
+	for i := 0; i < 4; i++ {
+		theta := float64(i) * math.Pi / 2
+		if s.within(theta) {
+			r = r.Extend(s.Centre.Move(Polar{s.R, theta}))
+		}
+	}
+	return r
+}
+
+func (s Sector) Translate(v Pos) Sector {
+	s.Centre = s.Centre.Add(v)
+	return s
+}
+
+func (s Sector) Rotate(about Pos, theta float64) Sector {
+	s.Centre = s.Centre.RotateAbout(about, theta)
+	s.Facing = NormaliseAngle(s.Facing + theta)
+	return s
+}
+
+func (s Sector) boundary() []boundaryPiece {
+	from, to := s.arcEnds()
+	return []boundaryPiece{
+		{arc: &arc{centre: s.Centre, r: s.R, from: s.Facing - s.HalfAngle, sweep: 2 * s.HalfAngle}},
+		{seg: &LineSegment{From: s.Centre, To: from}},
+		{seg: &LineSegment{From: s.Centre, To: to}},
+	}
+}
+
+func (s Sector) anyPoint() Pos {
+	return s.Centre
+}
+
+// Polygon approximates the sector with n chords along the arc
+func (s Sector) Polygon(n int) Region {
+	pts := []Pos{s.Centre}
+	for i := 0; i <= n; i++ {
+		theta := s.Facing - s.HalfAngle + 2*s.HalfAngle*float64(i)/float64(n)
+		pts = append(pts, s.Centre.Move(Polar{s.R, theta}))
+	}
+	return NewRegion(pts)
+}
+
+// An arc of a circle going anticlockwise from the angle from
+type arc struct {
+	centre Pos
+	r      float64
+	from   float64
+	sweep  float64
+}
+
+func (a *arc) within(theta float64) bool {
+	d := math.Mod(theta-a.from, 2*math.Pi)
+	if d < 0 {
+		d += 2 * math.Pi
+	}
+	// Allow for rounding at the ends
+	const eps = 1e-12
+	return d <= a.sweep+eps || d >= 2*math.Pi-eps
+}
+
+func (a *arc) contains(p Pos) bool {
+	return a.within(a.centre.PolarTo(p).Theta)
+}
+
+// Points where the circle of radius r about c crosses ls
+func circleSegmentCrossings(c Pos, r float64, ls LineSegment) []Pos {
+	d := ls.To.Sub(ls.From)
+	f := ls.From.Sub(c)
+	a := d.Dot(d)
+	b := 2 * f.Dot(d)
+	cc := f.Dot(f) - r*r
+	if a == 0 {
+		if cc == 0 {
+			return []Pos{ls.From}
+		}
+		return nil
+	}
+	disc := b*b - 4*a*cc
+	if disc < 0 {
+		return nil
+	}
+	sq := math.Sqrt(disc)
+	var pts []Pos
+	for _, t := range []float64{(-b - sq) / (2 * a), (-b + sq) / (2 * a)} {
+		if t >= 0 && t <= 1 {
+			pts = append(pts, ls.From.Add(d.Scale(t)))
+		}
+	}
+	return pts
+}
+
+// Points where two circles cross
+func circleCrossings(c1 Pos, r1 float64, c2 Pos, r2 float64) []Pos {
+	d := c1.DistanceTo(c2)
+	if d == 0 || d > r1+r2 || d < math.Abs(r1-r2) {
+		return nil
+	}
+	along := (d*d + r1*r1 - r2*r2) / (2 * d)
+	h := math.Sqrt(math.Max(r1*r1-along*along, 0))
+	u := c2.Sub(c1).Scale(1 / d)
+	mid := c1.Add(u.Scale(along))
+	perp := u.Perp().Scale(h)
+	return []Pos{mid.Add(perp), mid.Sub(perp)}
+}
+
+// A piece of a shape's boundary, either a segment or an arc
+type boundaryPiece struct {
+	seg *LineSegment
+	arc *arc
+}
+
+func (bp boundaryPiece) crosses(other boundaryPiece) bool {
+	switch {
+	case bp.seg != nil && other.seg != nil:
+		return bp.seg.SegmentIntersectsLine(*other.seg)
+	case bp.arc != nil && other.seg != nil:
+		return other.crosses(bp)
+	case bp.seg != nil:
+		for _, p := range circleSegmentCrossings(other.arc.centre, other.arc.r, *bp.seg) {
+			if other.arc.contains(p) {
+				return true
+			}
+		}
+		return false
+	default:
+		a, b := bp.arc, other.arc
+		if a.centre == b.centre {
+			if a.r != b.r {
+				return false
+			}
+			// The same circle, do the arcs share an angle?
+			return a.within(b.from) || b.within(a.from)
+		}
+		for _, p := range circleCrossings(a.centre, a.r, b.centre, b.r) {
+			if a.contains(p) && b.contains(p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Overlaps is true if a and b share any point, touching included
+func Overlaps(a, b Shape) bool {
+	if !a.BoundingBox().Overlaps(b.BoundingBox()) {
+		return false
+	}
+	ra, okA := a.(Region)
+	rb, okB := b.(Region)
+	if okA && okB && ra.IsConvex() && rb.IsConvex() {
+		return separatingAxisOverlap(ra, rb)
+	}
+	if a.Contains(b.anyPoint()) || b.Contains(a.anyPoint()) {
+		return true
+	}
+	// Otherwise their boundaries must cross
+	for _, pa := range a.boundary() {
+		for _, pb := range b.boundary() {
+			if pa.crosses(pb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Separating axis test for convex polygons: they are apart if and only
+// if their projections onto the normal of some edge don't overlap
+func separatingAxisOverlap(a, b Region) bool {
+	for _, r := range []Region{a, b} {
+		for _, edge := range r.Edges() {
+			d := edge.To.Sub(edge.From)
+			if d == (Pos{}) {
+				continue
+			}
+			axis := d.Perp()
+			minA, maxA := project(a, axis)
+			minB, maxB := project(b, axis)
+			if maxA < minB || maxB < minA {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func project(r Region, axis Pos) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range r.points {
+		v := p.Dot(axis)
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+// ConvexHull is the smallest convex region holding all of pts, going
+// anticlockwise
+func ConvexHull(pts []Pos) Region {
+	sorted := make([]Pos, len(pts))
+	copy(sorted, pts)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+
+	// Andrew's monotone chain, lower then upper
+	var hull []Pos
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range sorted {
+			for len(hull) >= start+2 && Orientation(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		// The last point starts the other chain
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		}
+	}
+	return NewRegion(hull)
+}
@@ -0,0 +1,232 @@
+package pos
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+func TestShapeMeasures(t *testing.T) {
+	square := NewRegion([]Pos{{0, 0}, {2, 0}, {2, 2}, {0, 2}})
+	triangle := NewRegion([]Pos{{0, 0}, {0, 3}, {3, 0}})
+	quarter := NewSector(Pos{0, 0}, 2, math.Pi/4, math.Pi/4)
+
+	testCases := []struct {
+		shape    Shape
+		area     float64
+		centroid Pos
+		box      Rect
+	}{
+		{square, 4, Pos{1, 1}, Rect{Pos{0, 0}, Pos{2, 2}}},
+		{triangle, 4.5, Pos{1, 1}, Rect{Pos{0, 0}, Pos{3, 3}}},
+		{NewCircle(Pos{1, -1}, 2), 4 * math.Pi, Pos{1, -1}, Rect{Pos{-1, -3}, Pos{3, 1}}},
+		{quarter, math.Pi, Pos{8 / (3 * math.Pi), 8 / (3 * math.Pi)}, Rect{Pos{0, 0}, Pos{2, 2}}},
+		{NewSector(Pos{0, 0}, 1, 0, math.Pi/2), math.Pi / 2, Pos{4 / (3 * math.Pi), 0}, Rect{Pos{0, -1}, Pos{1, 1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		if !approxEqual(tc.shape.Area(), tc.area) {
+			t.Fatalf("Got area %g expected %g", tc.shape.Area(), tc.area)
+		}
+		if !tc.shape.Centroid().Equals(tc.centroid) {
+			t.Fatalf("Got centroid %s expected %s", tc.shape.Centroid(), tc.centroid)
+		}
+		box := tc.shape.BoundingBox()
+		if !box.Min.Equals(tc.box.Min) || !box.Max.Equals(tc.box.Max) {
+			t.Fatalf("Got box %s expected %s", box, tc.box)
+		}
+	}
+}
+
+func TestShapeRotate(t *testing.T) {
+	square := NewRegion([]Pos{{0, 0}, {2, 0}, {2, 2}, {0, 2}})
+	rotated := square.Rotate(Pos{0, 0}, math.Pi/2)
+	if !rotated.Centroid().Equals(Pos{-1, 1}) || !approxEqual(rotated.Area(), 4) {
+		t.Fatalf("Got %s", rotated)
+	}
+
+	s := NewSector(Pos{1, 0}, 1, 0, 0.5).Rotate(Pos{0, 0}, math.Pi)
+	if !s.Centre.Equals(Pos{-1, 0}) || !approxEqual(math.Abs(s.Facing), math.Pi) {
+		t.Fatalf("Got %s", s)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	square := NewRegion([]Pos{{0, 0}, {2, 0}, {2, 2}, {0, 2}})
+	// A cross over the square, no corner inside the other
+	bar := NewRegion([]Pos{{-1, 0.5}, {3, 0.5}, {3, 1.5}, {-1, 1.5}})
+	ell := NewRegion([]Pos{{0, 0}, {4, 0}, {4, 1}, {1, 1}, {1, 4}, {0, 4}})
+	cone := NewSector(Pos{0, 0}, 5, 0, math.Pi/8)
+
+	testCases := []struct {
+		a, b     Shape
+		expected bool
+	}{
+		{square, bar, true},
+		{bar, square, true},
+		{square, square.Translate(Pos{2, 0}), true},
+		{square, square.Translate(Pos{2.1, 0}), false},
+		{ell, NewCircle(Pos{3, 3}, 1.5), false},
+		{ell, NewCircle(Pos{3, 3}, 2.1), true},
+		// Circle crosses only the arc
+		{cone, NewCircle(Pos{5.5, 0}, 1), true},
+		{cone, NewCircle(Pos{6.5, 0}, 1), false},
+		// Circle beside the cone, inside its bounding box
+		{cone, NewCircle(Pos{3, 2}, 0.5), false},
+		{cone, NewCircle(Pos{3, 2}, 1), true},
+		{cone, NewSector(Pos{5, 1}, 2, math.Pi, 0.3), true},
+		{cone, NewSector(Pos{5, 3}, 1, math.Pi, 0.3), false},
+		{cone, bar, true},
+		{NewCircle(Pos{1, 1}, 0.2), square, true},
+		{NewCircle(Pos{0, 0}, 10), square, true},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := Overlaps(tc.a, tc.b)
+		if got != tc.expected {
+			t.Fatalf("Got %v expected %v", got, tc.expected)
+		}
+		if Overlaps(tc.b, tc.a) != got {
+			t.Fatalf("Not symmetric")
+		}
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	hull := ConvexHull([]Pos{{0, 0}, {1, 1}, {2, 0}, {2, 2}, {0, 2}, {1, 0}, {0.5, 1.5}})
+	if !approxEqual(hull.SignedArea(), 4) || len(hull.Points()) != 4 || !hull.IsConvex() {
+		t.Fatalf("Got %s", hull)
+	}
+}
+
+func regionsArea(rs []Region) float64 {
+	total := 0.0
+	for _, r := range rs {
+		total += r.SignedArea()
+	}
+	return total
+}
+
+func TestSetOperations(t *testing.T) {
+	square := NewRegion([]Pos{{0, 0}, {2, 0}, {2, 2}, {0, 2}})
+	shifted := square.Translate(Pos{1, 1})
+	beside := square.Translate(Pos{2, 0})
+	inner := NewRegion([]Pos{{0.5, 0.5}, {1.5, 0.5}, {1.5, 1.5}, {0.5, 1.5}})
+
+	testCases := []struct {
+		name             string
+		a, b             Region
+		union, inter, ab float64
+		unionRegions     int
+	}{
+		{"crossing", square, shifted, 7, 1, 3, 1},
+		{"sharing an edge", square, beside, 8, 0, 4, 1},
+		{"nested", square, inner, 4, 1, 3, 1},
+		{"apart", square, square.Translate(Pos{5, 0}), 8, 0, 4, 2},
+		{"same", square, square, 4, 4, 0, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %s", tc.name)
+		union := tc.a.Union(tc.b)
+		if !approxEqual(regionsArea(union), tc.union) || len(union) != tc.unionRegions {
+			t.Fatalf("Got union %v", union)
+		}
+		if got := regionsArea(tc.a.Intersection(tc.b)); !approxEqual(got, tc.inter) {
+			t.Fatalf("Got intersection area %g expected %g", got, tc.inter)
+		}
+		if got := regionsArea(tc.a.Difference(tc.b)); !approxEqual(got, tc.ab) {
+			t.Fatalf("Got difference area %g expected %g", got, tc.ab)
+		}
+	}
+}
+
+// Pairs of convex polygons on a coarse grid, so edges often line up
+type quickConvexPair struct {
+	A, B Region
+	Q    Pos
+}
+
+func (quickConvexPair) Generate(rnd *rand.Rand, size int) reflect.Value {
+	coord := func() float64 { return float64(rnd.Intn(9) - 4) }
+	poly := func() Region {
+		for {
+			pts := make([]Pos, 3+rnd.Intn(5))
+			for i := range pts {
+				pts[i] = Pos{coord(), coord()}
+			}
+			hull := ConvexHull(pts)
+			if hull.IsConvex() {
+				return hull
+			}
+		}
+	}
+	q := Pos{coord() + rnd.Float64(), coord() + rnd.Float64()}
+	return reflect.ValueOf(quickConvexPair{poly(), poly(), q})
+}
+
+func TestQuickSetOperations(t *testing.T) {
+	f := func(qp quickConvexPair) bool {
+		a, b := qp.A.Area(), qp.B.Area()
+		union := regionsArea(qp.A.Union(qp.B))
+		inter := regionsArea(qp.A.Intersection(qp.B))
+		diff := regionsArea(qp.A.Difference(qp.B))
+		if !approxEqual(union+inter, a+b) || !approxEqual(diff+inter, a) {
+			t.Logf("%s and %s: union %g intersection %g difference %g", qp.A, qp.B, union, inter, diff)
+			return false
+		}
+		if inter > 1e-9 && !Overlaps(qp.A, qp.B) {
+			return false
+		}
+		return true
+	}
+	err := quick.Check(f, &quick.Config{MaxCount: 3000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuickSetOperationsContain(t *testing.T) {
+	inside := func(rs []Region, q Pos) bool {
+		wn := 0
+		for _, r := range rs {
+			wn += r.WindingNumber(q)
+		}
+		return wn != 0
+	}
+	f := func(qp quickConvexPair) bool {
+		if nearEdge(qp.A, qp.Q, 1e-6) || nearEdge(qp.B, qp.Q, 1e-6) {
+			return true
+		}
+		inA, inB := qp.A.Contains(qp.Q), qp.B.Contains(qp.Q)
+		return inside(qp.A.Union(qp.B), qp.Q) == (inA || inB) &&
+			inside(qp.A.Intersection(qp.B), qp.Q) == (inA && inB) &&
+			inside(qp.A.Difference(qp.B), qp.Q) == (inA && !inB)
+	}
+	err := quick.Check(f, &quick.Config{MaxCount: 3000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SAT agrees with testing the boundaries and corners
+func TestQuickSeparatingAxis(t *testing.T) {
+	f := func(qp quickConvexPair) bool {
+		sat := separatingAxisOverlap(qp.A, qp.B)
+		general := qp.A.Contains(qp.B.anyPoint()) || qp.B.Contains(qp.A.anyPoint())
+		for _, ea := range qp.A.Edges() {
+			for _, eb := range qp.B.Edges() {
+				general = general || ea.SegmentIntersectsLine(eb)
+			}
+		}
+		return sat == general
+	}
+	err := quick.Check(f, &quick.Config{MaxCount: 3000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,316 @@
+// Package prom exposes metrics in the Prometheus text format, enough
+// for counters, gauges and histograms without pulling in the client
+// library
+package prom
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	name() string
+	writeText(w io.Writer) error
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.metrics {
+		if existing.name() == m.name() {
+			panic(fmt.Sprintf("metric registered twice: %s", m.name()))
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric, in registration order
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		err := m.writeText(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	err := r.WriteText(rw)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("Can't write metrics: %s", err), http.StatusInternalServerError)
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value as the text format wants it, which
+// is only backslash, double quote and newline
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func writeHeader(w io.Writer, name, help, typ string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	return err
+}
+
+type desc struct {
+	n, help string
+}
+
+func (d desc) name() string {
+	return d.n
+}
+
+// value is a float64 safe for concurrent use
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+func (v *value) set(f float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.v = f
+}
+
+func (v *value) add(f float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.v += f
+}
+
+type Counter struct {
+	desc
+	value
+}
+
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{desc: desc{name, help}}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Inc() {
+	c.add(1)
+}
+
+func (c *Counter) Add(f float64) {
+	if f < 0 {
+		panic("counters only go up")
+	}
+	c.add(f)
+}
+
+func (c *Counter) writeText(w io.Writer) error {
+	err := writeHeader(w, c.n, c.help, "counter")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", c.n, formatFloat(c.get()))
+	return err
+}
+
+type Gauge struct {
+	desc
+	value
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{desc: desc{name, help}}
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Set(f float64) {
+	g.set(f)
+}
+
+func (g *Gauge) writeText(w io.Writer) error {
+	err := writeHeader(w, g.n, g.help, "gauge")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", g.n, formatFloat(g.get()))
+	return err
+}
+
+// GaugeFunc is read when scraped, f must be safe to call from any goroutine
+type GaugeFunc struct {
+	desc
+	f func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name, help}, f: f}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) writeText(w io.Writer) error {
+	err := writeHeader(w, g.n, g.help, "gauge")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", g.n, formatFloat(g.f()))
+	return err
+}
+
+// GaugeVec is a gauge with one label
+type GaugeVec struct {
+	desc
+	label string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (r *Registry) NewGaugeVec(name, help, label string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name, help}, label: label, values: make(map[string]float64)}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeVec) Set(labelValue string, f float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[labelValue] = f
+}
+
+func (g *GaugeVec) writeText(w io.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	err := writeHeader(w, g.n, g.help, "gauge")
+	if err != nil {
+		return err
+	}
+	labelValues := make([]string, 0, len(g.values))
+	for lv := range g.values {
+		labelValues = append(labelValues, lv)
+	}
+	sort.Strings(labelValues)
+	for _, lv := range labelValues {
+		_, err = fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", g.n, g.label, escapeLabel(lv), formatFloat(g.values[lv]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Histogram struct {
+	desc
+	upperBounds []float64
+
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	upperBounds := make([]float64, len(buckets))
+	copy(upperBounds, buckets)
+	sort.Float64s(upperBounds)
+	h := &Histogram{
+		desc:        desc{name, help},
+		upperBounds: upperBounds,
+		counts:      make([]uint64, len(upperBounds)),
+	}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(f float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i := sort.SearchFloat64s(h.upperBounds, f)
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += f
+}
+
+func (h *Histogram) writeText(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := writeHeader(w, h.n, h.help, "histogram")
+	if err != nil {
+		return err
+	}
+	var lines []string
+	var cumulative uint64
+	for i, ub := range h.upperBounds {
+		cumulative += h.counts[i]
+		lines = append(lines, fmt.Sprintf("%s_bucket{le=%q} %d", h.n, formatFloat(ub), cumulative))
+	}
+	lines = append(lines,
+		fmt.Sprintf("%s_bucket{le=\"+Inf\"} %d", h.n, h.count),
+		fmt.Sprintf("%s_sum %s", h.n, formatFloat(h.sum)),
+		fmt.Sprintf("%s_count %d", h.n, h.count),
+	)
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// CounterFunc is read when scraped, for counts kept elsewhere. f must
+// be safe to call from any goroutine
+type CounterFunc struct {
+	desc
+	f func() float64
+}
+
+func (r *Registry) NewCounterFunc(name, help string, f func() float64) *CounterFunc {
+	c := &CounterFunc{desc: desc{name, help}, f: f}
+	r.register(c)
+	return c
+}
+
+func (c *CounterFunc) writeText(w io.Writer) error {
+	err := writeHeader(w, c.n, c.help, "counter")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", c.n, formatFloat(c.f()))
+	return err
+}
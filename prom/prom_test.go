@@ -0,0 +1,76 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("ticks_total", "Ticks so far.")
+	g := r.NewGauge("paused", "1 if paused.")
+	r.NewGaugeFunc("clients", "Connected clients.", func() float64 { return 3 })
+	gv := r.NewGaugeVec("entities", "Entities by kind.", "kind")
+	h := r.NewHistogram("tick_seconds", "Time per tick.", []float64{0.1, 1})
+
+	c.Inc()
+	c.Add(2)
+	g.Set(1)
+	gv.Set("food", 5)
+	gv.Set("creech", 2)
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var sb strings.Builder
+	err := r.WriteText(&sb)
+	if err != nil {
+		t.Fatalf("WriteText: %s", err)
+	}
+	expected := `# HELP ticks_total Ticks so far.
+# TYPE ticks_total counter
+ticks_total 3
+# HELP paused 1 if paused.
+# TYPE paused gauge
+paused 1
+# HELP clients Connected clients.
+# TYPE clients gauge
+clients 3
+# HELP entities Entities by kind.
+# TYPE entities gauge
+entities{kind="creech"} 2
+entities{kind="food"} 5
+# HELP tick_seconds Time per tick.
+# TYPE tick_seconds histogram
+tick_seconds_bucket{le="0.1"} 2
+tick_seconds_bucket{le="1"} 3
+tick_seconds_bucket{le="+Inf"} 4
+tick_seconds_sum 5.65
+tick_seconds_count 4
+`
+	if sb.String() != expected {
+		t.Fatalf("got:\n%s\nexpected:\n%s", sb.String(), expected)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{"food", "food"},
+		{"a\tb", "a\tb"},
+		{"café", "café"},
+		{`say "hi"`, `say \"hi\"`},
+		{`a\b`, `a\\b`},
+		{"a\nb", `a\nb`},
+	}
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := escapeLabel(tc.in)
+		if got != tc.expected {
+			t.Fatalf("Got %q expected %q", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package creech
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// A recording is a stream of JSON values, a RecordingHeader followed by
+// a Snapshot per tick. It is gzipped if the file name ends in ".gz".
+
+// Version 1 recordings have no config and used the defaults
+const recordingVersion = 2
+
+type RecordingHeader struct {
+	Version   int
+	Seed      int64
+	WorldSize Pos
+	TickDur   time.Duration
+	Config    *Config   `json:",omitempty"`
+	Scenario  *Scenario `json:",omitempty"`
+}
+
+func (h *RecordingHeader) config() Config {
+	if h.Config == nil {
+		return DefaultConfig()
+	}
+	return *h.Config
+}
+
+type Recording struct {
+	Header RecordingHeader
+	Frames []Snapshot
+}
+
+type Recorder struct {
+	f   *os.File
+	gz  *gzip.Writer // nil if not compressing
+	enc *json.Encoder
+}
+
+func NewRecorder(path string, header RecordingHeader) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't create recording: %w", err)
+	}
+	r := &Recorder{f: f}
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		r.gz = gzip.NewWriter(f)
+		w = r.gz
+	}
+	r.enc = json.NewEncoder(w)
+	err = r.enc.Encode(header)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't write recording header: %w", err)
+	}
+	return r, nil
+}
+
+func (r *Recorder) Record(snap Snapshot) error {
+	return r.enc.Encode(snap)
+}
+
+// Close flushes the recording, it is truncated without this
+func (r *Recorder) Close() error {
+	if r.gz != nil {
+		err := r.gz.Close()
+		if err != nil {
+			r.f.Close()
+			return err
+		}
+	}
+	return r.f.Close()
+}
+
+// Save writes the world as it is now as a recording of one frame,
+// which can be replayed
+func (g *Game) Save(path string) error {
+	r, err := NewRecorder(path, g.RecordingHeader())
+	if err != nil {
+		return err
+	}
+	err = r.Record(g.Snapshot())
+	if err != nil {
+		r.Close()
+		return fmt.Errorf("can't write snapshot: %w", err)
+	}
+	return r.Close()
+}
+
+func LoadRecording(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open recording: %w", err)
+	}
+	defer f.Close()
+
+	var rd io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress recording: %w", err)
+		}
+		defer gz.Close()
+		rd = gz
+	}
+
+	dec := json.NewDecoder(rd)
+	var rec Recording
+	err = dec.Decode(&rec.Header)
+	if err != nil {
+		return nil, fmt.Errorf("can't read recording header: %w", err)
+	}
+	if rec.Header.Version < 1 || rec.Header.Version > recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", rec.Header.Version)
+	}
+	for {
+		var snap Snapshot
+		err = dec.Decode(&snap)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		// An unflushed recording ends part way through a frame,
+		// keep what we have
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read frame %d: %w", len(rec.Frames), err)
+		}
+		// Playback rebuilds each frame, so check now that it can
+		_, err = stateFromSnapshot(snap)
+		if err != nil {
+			return nil, fmt.Errorf("bad frame %d: %w", len(rec.Frames), err)
+		}
+		rec.Frames = append(rec.Frames, snap)
+	}
+	return &rec, nil
+}
+
+// Verify re-simulates a recording from its seed and checks each tick
+// matches. Edits made while recording will show up as a divergence.
+func Verify(rec *Recording) error {
+	g := NewGame(render.NewNull(), rec.Header.TickDur, rec.Header.Seed)
+	var err error
+	if rec.Header.Scenario != nil {
+		err = g.SetScenario(rec.Header.Scenario)
+	} else {
+		err = g.SetConfig(rec.Header.config())
+	}
+	if err != nil {
+		return fmt.Errorf("Can't verify: %w", err)
+	}
+	g.worldSize = rec.Header.WorldSize
+	err = g.Init()
+	if err != nil {
+		return err
+	}
+	for _, want := range rec.Frames {
+		// Fast forward over anything not recorded
+		for g.ticks < want.Tick {
+			g.advance()
+		}
+		got := g.state.Snapshot(g.ticks)
+		diff := diffSnapshots(got, want)
+		if diff != "" {
+			return fmt.Errorf("diverged at tick %d: %s", want.Tick, diff)
+		}
+	}
+	return nil
+}
+
+// Describe the first difference, ignoring IDs, which older recordings
+// took from an unseeded source
+func diffSnapshots(got, want Snapshot) string {
+	if len(got.Creeches) != len(want.Creeches) {
+		return fmt.Sprintf("got %d creeches, want %d", len(got.Creeches), len(want.Creeches))
+	}
+	for i := range got.Creeches {
+		g, w := got.Creeches[i], want.Creeches[i]
+		g.ID, w.ID = 0, 0
+		if g != w {
+			return fmt.Sprintf("creech %d: got %+v want %+v", i, g, w)
+		}
+	}
+	if len(got.Food) != len(want.Food) {
+		return fmt.Sprintf("got %d food, want %d", len(got.Food), len(want.Food))
+	}
+	for i := range got.Food {
+		g, w := got.Food[i], want.Food[i]
+		g.ID, w.ID = 0, 0
+		if g != w {
+			return fmt.Sprintf("food %d: got %+v want %+v", i, g, w)
+		}
+	}
+	if len(got.Others) != len(want.Others) {
+		return fmt.Sprintf("got %d other entities, want %d", len(got.Others), len(want.Others))
+	}
+	for i := range got.Others {
+		g, w := got.Others[i], want.Others[i]
+		g.ID, w.ID = 0, 0
+		if g != w {
+			return fmt.Sprintf("entity %d: got %+v want %+v", i, g, w)
+		}
+	}
+	return ""
+}
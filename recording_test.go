@@ -0,0 +1,142 @@
+package creech
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbert/creech/render"
+)
+
+func TestRecordAndVerify(t *testing.T) {
+	for _, name := range []string{"rec.jsonl", "rec.jsonl.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+
+		g := NewGame(render.NewNull(), time.Millisecond, 42)
+		err := g.Init()
+		if err != nil {
+			t.Fatalf("Init: %s", err)
+		}
+		r, err := NewRecorder(path, g.RecordingHeader())
+		if err != nil {
+			t.Fatalf("NewRecorder: %s", err)
+		}
+		err = g.SetRecorder(r)
+		if err != nil {
+			t.Fatalf("SetRecorder: %s", err)
+		}
+		numTicks := 100
+		for i := 0; i < numTicks; i++ {
+			err = g.tick()
+			if err != nil {
+				t.Fatalf("tick: %s", err)
+			}
+		}
+		err = r.Close()
+		if err != nil {
+			t.Fatalf("Close: %s", err)
+		}
+
+		rec, err := LoadRecording(path)
+		if err != nil {
+			t.Fatalf("LoadRecording: %s", err)
+		}
+		// The start and after every tick
+		if len(rec.Frames) != numTicks+1 {
+			t.Fatalf("got %d frames expected %d", len(rec.Frames), numTicks+1)
+		}
+		err = Verify(rec)
+		if err != nil {
+			t.Fatalf("Verify: %s", err)
+		}
+
+		// Any change is a divergence
+		rec.Frames[numTicks/2].Creeches[0].Food += 0.001
+		err = Verify(rec)
+		if err == nil {
+			t.Fatalf("Expected divergence")
+		}
+		t.Logf("%s", err)
+	}
+}
+
+// Stepping while paused records each tick as running does
+func TestStepRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	g := NewGame(render.NewNull(), time.Millisecond, 42)
+	err := g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	r, err := NewRecorder(path, g.RecordingHeader())
+	if err != nil {
+		t.Fatalf("NewRecorder: %s", err)
+	}
+	err = g.SetRecorder(r)
+	if err != nil {
+		t.Fatalf("SetRecorder: %s", err)
+	}
+	steps := 0
+	g.OnStep(func(Snapshot) { steps++ })
+
+	numTicks := 10
+	for i := 0; i < numTicks; i++ {
+		if i%2 == 0 {
+			err = g.tick()
+		} else {
+			err = g.handleCommand(render.Command{What: render.CmdStep}, nil)
+		}
+		if err != nil {
+			t.Fatalf("tick %d: %s", i, err)
+		}
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	if !g.paused || steps != numTicks {
+		t.Fatalf("Got paused %v after %d steps expected paused after %d", g.paused, steps, numTicks)
+	}
+
+	rec, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("LoadRecording: %s", err)
+	}
+	if len(rec.Frames) != numTicks+1 {
+		t.Fatalf("got %d frames expected %d", len(rec.Frames), numTicks+1)
+	}
+	err = Verify(rec)
+	if err != nil {
+		t.Fatalf("Verify: %s", err)
+	}
+}
+
+// A recording whose entities share an ID can't be played back
+func TestLoadRecordingDuplicateID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	g := NewGame(render.NewNull(), time.Millisecond, 42)
+	err := g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	r, err := NewRecorder(path, g.RecordingHeader())
+	if err != nil {
+		t.Fatalf("NewRecorder: %s", err)
+	}
+	snap := g.Snapshot()
+	snap.Food = append(snap.Food, FoodSnapshot{ID: snap.Creeches[0].ID, Value: 1})
+	err = r.Record(snap)
+	if err != nil {
+		t.Fatalf("Record: %s", err)
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	_, err = LoadRecording(path)
+	if err == nil || !strings.Contains(err.Error(), "duplicate ID") {
+		t.Fatalf("Got error %v expected a duplicate ID", err)
+	}
+}
@@ -0,0 +1,148 @@
+package creech
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Registry holds every entity in the world by ID. IDs count up from 1
+// and are never reused, so a run gives the same IDs each time. The
+// zero value is empty and ready to use.
+type Registry struct {
+	nextID   int64
+	byID     map[int64]Entity
+	order    []Entity // In the order added, so iteration is repeatable
+	onAdd    []func(Entity)
+	onRemove []func(Entity)
+}
+
+// Entities get their ID from the registry
+type registered interface {
+	Entity
+	setID(id int64)
+}
+
+// Add gives e the next ID and holds it, e must embed BaseEntity
+func (r *Registry) Add(e Entity) (int64, error) {
+	re, ok := e.(registered)
+	if !ok {
+		return 0, errors.New("entity must embed BaseEntity")
+	}
+	return r.add(re), nil
+}
+
+func (r *Registry) add(e registered) int64 {
+	if r.nextID == 0 {
+		r.nextID = 1
+	}
+	id := r.nextID
+	e.setID(id)
+	err := r.insert(e)
+	if err != nil {
+		panic(fmt.Sprintf("wtf: %s", err))
+	}
+	for _, f := range r.onAdd {
+		f(e)
+	}
+	return id
+}
+
+// Hold e with the ID it already has, e.g. from a snapshot
+func (r *Registry) insert(e Entity) error {
+	if r.byID == nil {
+		r.byID = make(map[int64]Entity)
+	}
+	id := e.ID()
+	if _, ok := r.byID[id]; ok {
+		return fmt.Errorf("duplicate ID %d", id)
+	}
+	r.byID[id] = e
+	r.order = append(r.order, e)
+	if id >= r.nextID {
+		r.nextID = id + 1
+	}
+	return nil
+}
+
+// Remove drops the entity with this ID, returning it or nil
+func (r *Registry) Remove(id int64) Entity {
+	e, ok := r.byID[id]
+	if !ok {
+		return nil
+	}
+	delete(r.byID, id)
+	for i, o := range r.order {
+		if o == e {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	for _, f := range r.onRemove {
+		f(e)
+	}
+	return e
+}
+
+// Get finds the entity with this ID, or nil
+func (r *Registry) Get(id int64) Entity {
+	return r.byID[id]
+}
+
+func (r *Registry) Len() int {
+	return len(r.order)
+}
+
+// Entities are all of them, in the order added
+func (r *Registry) Entities() []Entity {
+	es := make([]Entity, len(r.order))
+	copy(es, r.order)
+	return es
+}
+
+// OnAdd calls f with each entity added from now on
+func (r *Registry) OnAdd(f func(Entity)) {
+	r.onAdd = append(r.onAdd, f)
+}
+
+// OnRemove calls f with each entity removed from now on
+func (r *Registry) OnRemove(f func(Entity)) {
+	r.onRemove = append(r.onRemove, f)
+}
+
+// Lookup finds the entity with this ID if it is a T
+func Lookup[T Entity](r *Registry, id int64) (T, bool) {
+	t, ok := r.byID[id].(T)
+	return t, ok
+}
+
+// All are the entities which are a T, in the order added
+func All[T Entity](r *Registry) []T {
+	var ts []T
+	for _, e := range r.order {
+		if t, ok := e.(T); ok {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// Filter are the entities which are a T and match keep
+func Filter[T Entity](r *Registry, keep func(T) bool) []T {
+	var ts []T
+	for _, e := range r.order {
+		if t, ok := e.(T); ok && keep(t) {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+func Count[T Entity](r *Registry) int {
+	n := 0
+	for _, e := range r.order {
+		if _, ok := e.(T); ok {
+			n++
+		}
+	}
+	return n
+}
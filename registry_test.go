@@ -0,0 +1,98 @@
+package creech
+
+import (
+	"testing"
+
+	. "github.com/jbert/creech/pos"
+)
+
+func TestRegistry(t *testing.T) {
+	var r Registry
+	var added, removed []int64
+	r.OnAdd(func(e Entity) { added = append(added, e.ID()) })
+	r.OnRemove(func(e Entity) { removed = append(removed, e.ID()) })
+
+	bob := NewCreech("bob", Pos{X: 0, Y: 0}, DefaultParams())
+	f1 := NewFood(1)
+	alice := NewCreech("alice", Pos{X: 1, Y: 1}, DefaultParams())
+	f2 := NewFood(2)
+	for i, e := range []Entity{bob, f1, alice, f2} {
+		id, err := r.Add(e)
+		if err != nil || id != int64(i+1) || e.ID() != id {
+			t.Fatalf("got ID %d for entity %d", id, i)
+		}
+	}
+
+	c, ok := Lookup[*Creech](&r, alice.ID())
+	if !ok || c != alice {
+		t.Fatalf("got %v looking up alice", c)
+	}
+	_, ok = Lookup[*Food](&r, alice.ID())
+	if ok {
+		t.Fatalf("found alice as food")
+	}
+	creeches := All[*Creech](&r)
+	if len(creeches) != 2 || creeches[0] != bob || creeches[1] != alice {
+		t.Fatalf("got creeches %v", creeches)
+	}
+	big := Filter(&r, func(f *Food) bool { return f.value > 1 })
+	if len(big) != 1 || big[0] != f2 || Count[*Food](&r) != 2 {
+		t.Fatalf("got big food %v", big)
+	}
+
+	if r.Remove(f1.ID()) != Entity(f1) || r.Remove(f1.ID()) != nil || r.Get(f1.ID()) != nil {
+		t.Fatalf("f1 not removed once")
+	}
+	// IDs aren't reused
+	f3 := NewFood(3)
+	r.add(f3)
+	if f3.ID() != 5 || r.Len() != 4 {
+		t.Fatalf("got ID %d with %d entities", f3.ID(), r.Len())
+	}
+	es := r.Entities()
+	if es[0] != Entity(bob) || es[3] != Entity(f3) {
+		t.Fatalf("got entities %v", es)
+	}
+	if len(added) != 5 || len(removed) != 1 || removed[0] != 2 {
+		t.Fatalf("got hooks added %v removed %v", added, removed)
+	}
+}
+
+func TestRegistryInsert(t *testing.T) {
+	snap := Snapshot{
+		Creeches: []CreechSnapshot{{ID: 7, Name: "bob"}},
+		Food:     []FoodSnapshot{{ID: 3, Value: 1}},
+	}
+	s, err := stateFromSnapshot(snap)
+	if err != nil {
+		t.Fatalf("stateFromSnapshot: %s", err)
+	}
+	c, ok := Lookup[*Creech](&s.entities, 7)
+	if !ok || c.name != "bob" {
+		t.Fatalf("got %v for ID 7", c)
+	}
+	f := NewFood(1)
+	s.entities.add(f)
+	if f.ID() != 8 {
+		t.Fatalf("got ID %d after a snapshot, expected 8", f.ID())
+	}
+}
+
+// Has no BaseEntity, so can't be given an ID or moved
+type bareEntity struct{}
+
+func (bareEntity) ID() int64     { return 0 }
+func (bareEntity) Pos() Pos      { return Pos{} }
+func (bareEntity) Size() float64 { return 1 }
+
+func TestRegistryBareEntity(t *testing.T) {
+	var s State
+	_, err := s.entities.Add(bareEntity{})
+	if err == nil || s.entities.Len() != 0 {
+		t.Fatalf("Got %v with %d entities expected an error", err, s.entities.Len())
+	}
+	err = s.moveEntity(bareEntity{}, Pos{X: 1, Y: 1})
+	if err == nil {
+		t.Fatalf("expected an error moving a bare entity")
+	}
+}
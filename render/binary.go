@@ -0,0 +1,84 @@
+package render
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Compact encoding of a batch of DrawCommands, as one websocket message.
+// All values are little-endian.
+//
+//	uint16 number of new palette entries
+//	  each: uint8 R, G, B, A
+//	uint32 number of commands
+//	  each: uint8 What, uint8 flags (1 = DoFill),
+//	        uint16 line colour index, uint16 fill colour index,
+//	        uint32 entity ID (low bits, 0 for none),
+//	        uint16 number of points, then float32 X, Y for each point
+//
+// Colours are indices into a palette which lives as long as the
+// connection, each message carries only the entries it adds.
+type binaryEncoder struct {
+	palette map[[4]uint8]uint16
+}
+
+const binaryFlagFill = 1
+
+func (rgba RGBA) bytes() [4]uint8 {
+	f2b := func(f float64) uint8 { return uint8(math.Round(255 * math.Max(0, math.Min(1, f)))) }
+	return [4]uint8{f2b(rgba.R), f2b(rgba.G), f2b(rgba.B), f2b(rgba.A)}
+}
+
+func (e *binaryEncoder) colourIndex(rgba RGBA, added *[][4]uint8) uint16 {
+	if e.palette == nil {
+		e.palette = make(map[[4]uint8]uint16)
+	}
+	b := rgba.bytes()
+	i, ok := e.palette[b]
+	if !ok {
+		i = uint16(len(e.palette))
+		e.palette[b] = i
+		*added = append(*added, b)
+	}
+	return i
+}
+
+func (e *binaryEncoder) Encode(cmds []DrawCommand) []byte {
+	var added [][4]uint8
+	var body []byte
+	body = appendUint32(body, uint32(len(cmds)))
+	for _, cmd := range cmds {
+		var flags uint8
+		if cmd.DoFill {
+			flags |= binaryFlagFill
+		}
+		body = append(body, uint8(cmd.What), flags)
+		body = appendUint16(body, e.colourIndex(cmd.LineColour, &added))
+		body = appendUint16(body, e.colourIndex(cmd.FillColour, &added))
+		body = appendUint32(body, uint32(cmd.ID))
+		body = appendUint16(body, uint16(len(cmd.Points)))
+		for _, p := range cmd.Points {
+			body = appendUint32(body, math.Float32bits(float32(p.X)))
+			body = appendUint32(body, math.Float32bits(float32(p.Y)))
+		}
+	}
+
+	buf := make([]byte, 0, 2+4*len(added)+len(body))
+	buf = appendUint16(buf, uint16(len(added)))
+	for _, b := range added {
+		buf = append(buf, b[:]...)
+	}
+	return append(buf, body...)
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jbert/creech/pos"
+)
+
+func TestBinaryEncode(t *testing.T) {
+	var enc binaryEncoder
+
+	frame := []DrawCommand{
+		{What: StartFrame},
+		Poly([]pos.Pos{{X: 1, Y: 2}}),
+	}
+	frame[1].ID = 7
+	got := enc.Encode(frame)
+	expected := []byte{
+		// Palette: zero value, Black, White
+		3, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 255,
+		255, 255, 255, 255,
+		// Commands
+		2, 0, 0, 0,
+		byte(StartFrame), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		byte(DrawPoly), 0, 1, 0, 2, 0, 7, 0, 0, 0, 1, 0,
+		0x00, 0x00, 0x80, 0x3f, // float32 1.0
+		0x00, 0x00, 0x00, 0x40, // float32 2.0
+	}
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("got %v expected %v", got, expected)
+	}
+
+	// Palette is only sent once per connection
+	got = enc.Encode(frame[:1])
+	expected = []byte{
+		0, 0,
+		1, 0, 0, 0,
+		byte(StartFrame), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("got %v expected %v", got, expected)
+	}
+}
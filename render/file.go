@@ -0,0 +1,255 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFinished is returned by renderers which have drawn all the
+// frames they were asked for, the game should stop
+var ErrFinished = errors.New("renderer finished")
+
+// File writes every Nth frame to a directory as SVG or PNG, and can
+// collect the same frames into an animated GIF
+type File struct {
+	opts FileOptions
+
+	width, height float64
+	frame         int // Frames seen
+	written       int // Frames captured
+	cmds          []DrawCommand
+	anim          *gif.GIF
+}
+
+// MaxGIFFrames bounds the memory held for a GIF, a few hundred MB for
+// the default world
+const MaxGIFFrames = 500
+
+type FileOptions struct {
+	Dir            string
+	Format         string // "svg", "png" or "" to write no frame files
+	Every          int    // Capture every Nth frame
+	Frames         int    // Stop after capturing this many, 0 for no limit
+	GIF            string // Path for an animated GIF, "" for none, needs Frames
+	GIFDelay       int    // Between GIF frames, in 100ths of a second
+	PixelsPerMetre float64
+}
+
+func NewFile(opts FileOptions) (*File, error) {
+	switch opts.Format {
+	case "", "svg", "png":
+	default:
+		return nil, fmt.Errorf("unknown file format: %s", opts.Format)
+	}
+	if opts.Format == "" && opts.GIF == "" {
+		return nil, errors.New("nothing to write, need a format or a GIF path")
+	}
+	// GIF frames are held in memory until Close
+	if opts.GIF != "" && (opts.Frames < 1 || opts.Frames > MaxGIFFrames) {
+		return nil, fmt.Errorf("a GIF needs a frame limit from 1 to %d, got %d", MaxGIFFrames, opts.Frames)
+	}
+	if opts.Every < 1 {
+		opts.Every = 1
+	}
+	if opts.GIFDelay < 1 {
+		opts.GIFDelay = 10
+	}
+	if opts.PixelsPerMetre <= 0 {
+		opts.PixelsPerMetre = 20
+	}
+	f := &File{opts: opts}
+	if opts.GIF != "" {
+		f.anim = &gif.GIF{}
+	}
+	return f, nil
+}
+
+func (f *File) Init(w, h float64) error {
+	f.width = w
+	f.height = h
+	if f.opts.Format == "" {
+		return nil
+	}
+	return os.MkdirAll(f.opts.Dir, 0755)
+}
+
+func (f *File) StartFrame() error {
+	f.cmds = nil
+	return nil
+}
+
+func (f *File) Draw(d Drawable) error {
+	for _, cmd := range d.Web() {
+		f.cmds = append(f.cmds, WrapCopies(cmd, f.width, f.height)...)
+	}
+	return nil
+}
+
+func (f *File) DrawBackground(cmds []DrawCommand) error {
+	for _, cmd := range cmds {
+		f.cmds = append(f.cmds, WrapCopies(cmd, f.width, f.height)...)
+	}
+	return nil
+}
+
+func (f *File) FinishFrame() error {
+	capture := f.frame%f.opts.Every == 0
+	f.frame++
+	if !capture {
+		return nil
+	}
+
+	name := filepath.Join(f.opts.Dir, fmt.Sprintf("frame-%06d.%s", f.written, f.opts.Format))
+	switch f.opts.Format {
+	case "svg":
+		err := writeFile(name, func(w io.Writer) error {
+			return f.WriteSVG(w)
+		})
+		if err != nil {
+			return err
+		}
+	case "png":
+		err := writeFile(name, func(w io.Writer) error {
+			return png.Encode(w, f.Image())
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if f.anim != nil {
+		f.anim.Image = append(f.anim.Image, toWebSafe(f.Image()))
+		f.anim.Delay = append(f.anim.Delay, f.opts.GIFDelay)
+	}
+
+	f.written++
+	if f.opts.Frames > 0 && f.written >= f.opts.Frames {
+		err := f.Close()
+		if err != nil {
+			return err
+		}
+		return ErrFinished
+	}
+	return nil
+}
+
+// Close writes the GIF, if we are making one, only the first time
+func (f *File) Close() error {
+	anim := f.anim
+	f.anim = nil
+	if anim == nil || len(anim.Image) == 0 {
+		return nil
+	}
+	return writeFile(f.opts.GIF, func(w io.Writer) error {
+		return gif.EncodeAll(w, anim)
+	})
+}
+
+// Nearest web safe colour for each pixel. draw.Draw does this too, but
+// searches the palette for every pixel which is too slow for long runs.
+func toWebSafe(img *image.RGBA) *image.Paletted {
+	paletted := image.NewPaletted(img.Bounds(), palette.WebSafe)
+	// Six levels per channel, 0x33 apart, indexed as 36r + 6g + b
+	level := func(v uint8) int { return (int(v) + 0x33/2) / 0x33 }
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			pix := img.Pix[i : i+3 : i+3]
+			paletted.SetColorIndex(x, y, uint8(36*level(pix[0])+6*level(pix[1])+level(pix[2])))
+		}
+	}
+	return paletted
+}
+
+func writeFile(name string, write func(w io.Writer) error) error {
+	fh, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("can't create %s: %w", name, err)
+	}
+	err = write(fh)
+	if err != nil {
+		fh.Close()
+		return fmt.Errorf("can't write %s: %w", name, err)
+	}
+	return fh.Close()
+}
+
+func (f *File) pixelSize() (int, int) {
+	return int(f.width * f.opts.PixelsPerMetre), int(f.height * f.opts.PixelsPerMetre)
+}
+
+// World co-ordinates are centred on the origin, y up
+func (f *File) toPixel(x, y float64) pos2 {
+	return pos2{
+		x: (x + f.width/2) * f.opts.PixelsPerMetre,
+		y: (f.height/2 - y) * f.opts.PixelsPerMetre,
+	}
+}
+
+// Image rasterises the current frame
+func (f *File) Image() *image.RGBA {
+	w, h := f.pixelSize()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, cmd := range f.cmds {
+		if cmd.What != DrawPoly {
+			continue
+		}
+		pts := make([]pos2, len(cmd.Points))
+		for i, p := range cmd.Points {
+			pts[i] = f.toPixel(p.X, p.Y)
+		}
+		if cmd.DoFill {
+			fillPolygon(img, pts, cmd.FillColour)
+		}
+		strokePath(img, pts, cmd.closed(), cmd.LineColour)
+	}
+	return img
+}
+
+func svgColour(rgba RGBA) (string, float64) {
+	b := rgba.bytes()
+	return fmt.Sprintf("rgb(%d,%d,%d)", b[0], b[1], b[2]), rgba.A
+}
+
+// WriteSVG writes the current frame
+func (f *File) WriteSVG(w io.Writer) error {
+	pw, ph := f.pixelSize()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", pw, ph, pw, ph)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="white"/>`+"\n", pw, ph)
+	for _, cmd := range f.cmds {
+		if cmd.What != DrawPoly {
+			continue
+		}
+		pts := make([]string, len(cmd.Points))
+		for i, p := range cmd.Points {
+			px := f.toPixel(p.X, p.Y)
+			pts[i] = fmt.Sprintf("%.2f,%.2f", px.x, px.y)
+		}
+		stroke, strokeOpacity := svgColour(cmd.LineColour)
+		fill, fillOpacity := "none", 1.0
+		if cmd.DoFill {
+			fill, fillOpacity = svgColour(cmd.FillColour)
+		}
+		// A polyline isn't stroked back to its start
+		shape := "polyline"
+		if cmd.closed() {
+			shape = "polygon"
+		}
+		fmt.Fprintf(&sb, `<%s points="%s" stroke="%s" stroke-opacity="%g" fill="%s" fill-opacity="%g"/>`+"\n",
+			shape, strings.Join(pts, " "), stroke, strokeOpacity, fill, fillOpacity)
+	}
+	sb.WriteString("</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
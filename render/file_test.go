@@ -0,0 +1,111 @@
+package render
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/jbert/creech/pos"
+)
+
+func TestFileImage(t *testing.T) {
+	f, err := NewFile(FileOptions{Format: "png", PixelsPerMetre: 10})
+	if err != nil {
+		t.Fatalf("NewFile: %s", err)
+	}
+	f.Init(4, 4)
+
+	// Filled square covering the top left quarter of the world
+	square := Poly([]pos.Pos{{X: -2, Y: 2}, {X: 0, Y: 2}, {X: 0, Y: 0}, {X: -2, Y: 0}})
+	square.DoFill = true
+	square.FillColour = RGBA{1, 0, 0, 1}
+	f.StartFrame()
+	f.cmds = append(f.cmds, square)
+
+	img := f.Image()
+	if img.Bounds() != image.Rect(0, 0, 40, 40) {
+		t.Fatalf("got bounds %v", img.Bounds())
+	}
+	testCases := []struct {
+		x, y     int
+		expected [4]uint8
+	}{
+		{10, 10, [4]uint8{255, 0, 0, 255}},     // Inside
+		{30, 30, [4]uint8{255, 255, 255, 255}}, // Outside
+		{0, 10, [4]uint8{0, 0, 0, 255}},        // Edge is stroked
+	}
+	for _, tc := range testCases {
+		i := img.PixOffset(tc.x, tc.y)
+		var got [4]uint8
+		copy(got[:], img.Pix[i:i+4])
+		if got != tc.expected {
+			t.Fatalf("pixel %d,%d: got %v expected %v", tc.x, tc.y, got, tc.expected)
+		}
+	}
+
+	var sb strings.Builder
+	err = f.WriteSVG(&sb)
+	if err != nil {
+		t.Fatalf("WriteSVG: %s", err)
+	}
+	expected := `<polygon points="0.00,0.00 20.00,0.00 20.00,20.00 0.00,20.00" stroke="rgb(0,0,0)" stroke-opacity="1" fill="rgb(255,0,0)" fill-opacity="1"/>`
+	if !strings.Contains(sb.String(), expected) {
+		t.Fatalf("SVG missing %s:\n%s", expected, sb.String())
+	}
+}
+
+// Open shapes aren't stroked back to their start
+func TestFileOpenShape(t *testing.T) {
+	f, err := NewFile(FileOptions{Format: "svg", PixelsPerMetre: 10})
+	if err != nil {
+		t.Fatalf("NewFile: %s", err)
+	}
+	f.Init(4, 4)
+	f.StartFrame()
+	// A V from the top left, down to the middle and back up
+	f.cmds = append(f.cmds, Poly([]pos.Pos{{X: -2, Y: 1.95}, {X: 0, Y: 0}, {X: 2, Y: 1.95}}))
+
+	img := f.Image()
+	testCases := []struct {
+		x, y     int
+		expected [4]uint8
+	}{
+		{20, 20, [4]uint8{0, 0, 0, 255}},      // On the V
+		{20, 0, [4]uint8{255, 255, 255, 255}}, // Where closing would go
+	}
+	for _, tc := range testCases {
+		i := img.PixOffset(tc.x, tc.y)
+		var got [4]uint8
+		copy(got[:], img.Pix[i:i+4])
+		if got != tc.expected {
+			t.Fatalf("pixel %d,%d: got %v expected %v", tc.x, tc.y, got, tc.expected)
+		}
+	}
+
+	var sb strings.Builder
+	err = f.WriteSVG(&sb)
+	if err != nil {
+		t.Fatalf("WriteSVG: %s", err)
+	}
+	if !strings.Contains(sb.String(), "<polyline ") || strings.Contains(sb.String(), "<polygon ") {
+		t.Fatalf("expected a polyline:\n%s", sb.String())
+	}
+}
+
+func TestFileGIFLimit(t *testing.T) {
+	testCases := []struct {
+		frames int
+		ok     bool
+	}{
+		{0, false},
+		{10, true},
+		{MaxGIFFrames + 1, false},
+	}
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		_, err := NewFile(FileOptions{GIF: "out.gif", Frames: tc.frames})
+		if (err == nil) != tc.ok {
+			t.Fatalf("Got %v expected ok %v", err, tc.ok)
+		}
+	}
+}
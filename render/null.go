@@ -0,0 +1,28 @@
+package render
+
+// Null draws nothing, for running the simulation headless
+type Null struct{}
+
+func NewNull() *Null {
+	return &Null{}
+}
+
+func (n *Null) Init(w, h float64) error {
+	return nil
+}
+
+func (n *Null) Resize(w, h float64) error {
+	return nil
+}
+
+func (n *Null) StartFrame() error {
+	return nil
+}
+
+func (n *Null) FinishFrame() error {
+	return nil
+}
+
+func (n *Null) Draw(d Drawable) error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Minimal software rasteriser for DrawCommands, the standard
+// library can composite images but not draw shapes
+
+// Composite c over the pixel at x, y
+func blend(img *image.RGBA, x, y int, c RGBA) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	a := math.Max(0, math.Min(1, c.A))
+	i := img.PixOffset(x, y)
+	pix := img.Pix[i : i+4 : i+4]
+	// Pix is alpha-premultiplied
+	for k, v := range []float64{c.R, c.G, c.B, 1} {
+		src := 255 * math.Max(0, math.Min(1, v)) * a
+		pix[k] = uint8(math.Round(src + float64(pix[k])*(1-a)))
+	}
+}
+
+// Even-odd fill, sampling at pixel centres
+func fillPolygon(img *image.RGBA, pts []pos2, c RGBA) {
+	if len(pts) < 3 {
+		return
+	}
+	minY, maxY := pts[0].y, pts[0].y
+	for _, p := range pts {
+		minY = math.Min(minY, p.y)
+		maxY = math.Max(maxY, p.y)
+	}
+
+	var xs []float64
+	for y := int(math.Floor(minY)); y <= int(math.Ceil(maxY)); y++ {
+		yc := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			a := pts[i]
+			b := pts[(i+1)%len(pts)]
+			if (a.y <= yc && yc < b.y) || (b.y <= yc && yc < a.y) {
+				xs = append(xs, a.x+(yc-a.y)*(b.x-a.x)/(b.y-a.y))
+			}
+		}
+		sort.Float64s(xs)
+		for k := 0; k+1 < len(xs); k += 2 {
+			from := int(math.Ceil(xs[k] - 0.5))
+			to := int(math.Floor(xs[k+1] - 0.5))
+			for x := from; x <= to; x++ {
+				blend(img, x, y, c)
+			}
+		}
+	}
+}
+
+// One pixel wide, back to the first point too if closed
+func strokePath(img *image.RGBA, pts []pos2, closed bool, c RGBA) {
+	for i := 0; i+1 < len(pts); i++ {
+		drawLine(img, pts[i], pts[i+1], c)
+	}
+	if closed && len(pts) > 2 {
+		drawLine(img, pts[len(pts)-1], pts[0], c)
+	}
+}
+
+func drawLine(img *image.RGBA, a, b pos2, c RGBA) {
+	dx := b.x - a.x
+	dy := b.y - a.y
+	steps := int(math.Ceil(math.Max(math.Abs(dx), math.Abs(dy))))
+	if steps == 0 {
+		blend(img, int(math.Floor(a.x)), int(math.Floor(a.y)), c)
+		return
+	}
+	// Each pixel once, the last is the first of the next line
+	for s := 0; s < steps; s++ {
+		t := float64(s) / float64(steps)
+		blend(img, int(math.Floor(a.x+t*dx)), int(math.Floor(a.y+t*dy)), c)
+	}
+}
+
+// A point in pixel co-ordinates
+type pos2 struct {
+	x, y float64
+}
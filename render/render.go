@@ -2,10 +2,14 @@ package render
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/jbert/creech/pos"
 )
 
+// Renderers holding files or connections also implement io.Closer, to
+// flush and release them once the game is done
 type Renderer interface {
 	Init(w, h float64) error
 	StartFrame() error
@@ -18,12 +22,99 @@ type Drawable interface {
 	Web() []DrawCommand
 }
 
+// Commander is implemented by renderers which can send control
+// commands back to the game (e.g. from a browser)
+type Commander interface {
+	Commands() <-chan Command
+}
+
+// Inspector is implemented by renderers which can display details
+// of the game and the selected entity
+type Inspector interface {
+	Inspect(i Inspection) error
+}
+
+// BackgroundDrawer is implemented by renderers which can show a layer
+// under everything else in the frame, e.g. temperature and nightfall
+type BackgroundDrawer interface {
+	DrawBackground(cmds []DrawCommand) error
+}
+
+// Resizer is implemented by renderers which can change the size of the
+// world they draw after Init, as a scenario's resize event needs
+type Resizer interface {
+	Resize(w, h float64) error
+}
+
+type Inspection struct {
+	Info     []InfoItem
+	Selected *pos.Pos // Position of the selected entity, if any
+}
+
+type CommandType int
+
+const (
+	CmdPause CommandType = iota
+	CmdResume
+	CmdStep
+	CmdSetTick
+	CmdInspect
+	CmdAddFood
+	CmdAddCreech
+	CmdMove
+	CmdDelete
+	CmdSeek
+	// Handled by the renderer, not sent to the game
+	CmdSetViewport
+)
+
+type Command struct {
+	What   CommandType
+	Tick   time.Duration      // CmdSetTick
+	Pos    pos.Pos            // CmdInspect, CmdAdd*, CmdMove (from), CmdDelete, CmdSetViewport (min)
+	To     pos.Pos            // CmdMove, CmdSetViewport (max)
+	Value  float64            // CmdAddFood
+	Name   string             // CmdAddCreech
+	Params map[string]float64 // CmdAddCreech, by name, unset are defaulted
+	Frame  int                // CmdSeek
+}
+
+// InfoItem is one line of displayed detail
+type InfoItem struct {
+	Key   string
+	Value string
+}
+
+func Info(key string, format string, args ...interface{}) InfoItem {
+	return InfoItem{Key: key, Value: fmt.Sprintf(format, args...)}
+}
+
+// Describer is implemented by drawables which can tell text
+// renderers more about themselves
+type Describer interface {
+	Describe() Description
+}
+
+type Description struct {
+	Kind    string // e.g. "creech" or "food"
+	Name    string
+	Species string
+	Pos     pos.Pos
+	Food    float64
+	Hunger  float64 // 0 when full, 1 when starving
+	Dead    bool
+	Plan    string
+}
+
 type DrawType int
 
 const (
 	StartFrame DrawType = iota
 	DrawPoly
 	FinishFrame
+	ShowInfo
+	// Points are the positions of every entity, for an overview map
+	DrawOverview
 )
 
 type RGBA struct {
@@ -43,11 +134,36 @@ type DrawCommand struct {
 	LineColour RGBA
 	DoFill     bool
 	FillColour RGBA
+	Info       []InfoItem `json:",omitempty"`
+	ID         int64      `json:",omitempty"` // Entity drawn, to follow it between frames
+}
+
+// Filled shapes are closed, others are open unless their last point
+// is their first, e.g. the arrow showing which way a creech faces
+func (dc DrawCommand) closed() bool {
+	n := len(dc.Points)
+	return dc.DoFill || (n > 2 && dc.Points[0] == dc.Points[n-1])
 }
 
 var Black = RGBA{0, 0, 0, 1}
 var White = RGBA{1, 1, 1, 1}
 
+// Bounding box of the points, false if there are none
+func (dc DrawCommand) Bounds() (pos.Pos, pos.Pos, bool) {
+	if len(dc.Points) == 0 {
+		return pos.Pos{}, pos.Pos{}, false
+	}
+	min := dc.Points[0]
+	max := dc.Points[0]
+	for _, p := range dc.Points[1:] {
+		min.X = math.Min(min.X, p.X)
+		min.Y = math.Min(min.Y, p.Y)
+		max.X = math.Max(max.X, p.X)
+		max.Y = math.Max(max.Y, p.Y)
+	}
+	return min, max, true
+}
+
 func Poly(pts []pos.Pos) DrawCommand {
 	return DrawCommand{
 		What:       DrawPoly,
@@ -57,3 +173,40 @@ func Poly(pts []pos.Pos) DrawCommand {
 		FillColour: White,
 	}
 }
+
+// The command, plus copies shifted by the world size for any part
+// which crosses the edge of a torus centred on the origin
+func WrapCopies(cmd DrawCommand, w, h float64) []DrawCommand {
+	min, max, ok := cmd.Bounds()
+	if !ok || cmd.What != DrawPoly {
+		return []DrawCommand{cmd}
+	}
+	shifts := func(min, max, size float64) []float64 {
+		s := []float64{0}
+		if max > size/2 {
+			s = append(s, -size)
+		}
+		if min < -size/2 {
+			s = append(s, size)
+		}
+		return s
+	}
+
+	var cmds []DrawCommand
+	for _, dx := range shifts(min.X, max.X, w) {
+		for _, dy := range shifts(min.Y, max.Y, h) {
+			if dx == 0 && dy == 0 {
+				cmds = append(cmds, cmd)
+				continue
+			}
+			v := pos.Pos{X: dx, Y: dy}
+			c := cmd // Struct copy
+			c.Points = make([]pos.Pos, len(cmd.Points))
+			for i, p := range cmd.Points {
+				c.Points[i] = p.Add(v)
+			}
+			cmds = append(cmds, c)
+		}
+	}
+	return cmds
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/jbert/creech/pos"
+)
+
+func TestWrapCopies(t *testing.T) {
+	w, h := 10.0, 10.0
+
+	testCases := []struct {
+		pts      []pos.Pos
+		expected []pos.Pos // First point of each copy
+	}{
+		// Inside
+		{[]pos.Pos{{X: 0, Y: 0}, {X: 1, Y: 1}}, []pos.Pos{{X: 0, Y: 0}}},
+		// Over the right edge
+		{[]pos.Pos{{X: 4, Y: 0}, {X: 6, Y: 0}}, []pos.Pos{{X: 4, Y: 0}, {X: -6, Y: 0}}},
+		// Over the bottom edge
+		{[]pos.Pos{{X: 0, Y: -4}, {X: 0, Y: -6}}, []pos.Pos{{X: 0, Y: -4}, {X: 0, Y: 6}}},
+		// Over the top right corner
+		{[]pos.Pos{{X: 4, Y: 4}, {X: 6, Y: 6}}, []pos.Pos{
+			{X: 4, Y: 4}, {X: 4, Y: -6}, {X: -6, Y: 4}, {X: -6, Y: -6},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("%+v", tc)
+		got := WrapCopies(Poly(tc.pts), w, h)
+		if len(got) != len(tc.expected) {
+			t.Fatalf("got %d copies, expected %d", len(got), len(tc.expected))
+		}
+		for i := range got {
+			if !got[i].Points[0].Equals(tc.expected[i]) {
+				t.Fatalf("copy %d: got %s expected %s", i, got[i].Points[0], tc.expected[i])
+			}
+		}
+	}
+}
@@ -21,6 +21,10 @@ func (s *Screen) Init(w, h float64) error {
 	return nil
 }
 
+func (s *Screen) Resize(w, h float64) error {
+	return s.Init(w, h)
+}
+
 func (s *Screen) resetBuffer() {
 	s.buffer = make([][]byte, s.height)
 	for j := range s.buffer {
@@ -46,12 +50,18 @@ func (s *Screen) FinishFrame() error {
 
 func (s *Screen) Draw(d Drawable) error {
 	i, j, b := d.Screen()
-	j = (-j + 3*s.height/2) % s.height
-	i = (i + 3*s.width/2) % s.width
+	// Origin in the centre, y increasing up the screen
+	j = wrapIndex(s.height/2-j, s.height)
+	i = wrapIndex(i+s.width/2, s.width)
 	s.buffer[j][i] = b
 	return nil
 }
 
+// i modulo n, in [0, n) even for negative i
+func wrapIndex(i, n int) int {
+	return ((i % n) + n) % n
+}
+
 func (s *Screen) clearScreen(w io.Writer) {
 	// From 'clear | hd'
 	clearByteSeq := []byte{0x1b, 0x5b, 0x48, 0x1b, 0x5b, 0x32, 0x4a, 0x1b, 0x5b, 0x33, 0x4a}
@@ -0,0 +1,24 @@
+package render
+
+import "testing"
+
+func TestWrapIndex(t *testing.T) {
+	testCases := []struct {
+		i, n, expected int
+	}{
+		{0, 40, 0},
+		{39, 40, 39},
+		{40, 40, 0},
+		{41, 40, 1},
+		{-1, 40, 39},
+		{-40, 40, 0},
+		{-41, 40, 39},
+	}
+
+	for _, tc := range testCases {
+		got := wrapIndex(tc.i, tc.n)
+		if got != tc.expected {
+			t.Fatalf("wrapIndex(%d, %d): got %d expected %d", tc.i, tc.n, got, tc.expected)
+		}
+	}
+}
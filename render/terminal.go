@@ -0,0 +1,267 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jbert/creech/pos"
+)
+
+// Terminal draws the world in ANSI colour, scaled to fit the terminal,
+// with a side panel listing the creeches and a legend underneath
+type Terminal struct {
+	out           io.Writer
+	fd            uintptr
+	width, height float64
+
+	glyphs []termGlyph
+	panel  []Description
+}
+
+type termGlyph struct {
+	b   byte
+	pos pos.Pos
+	d   Description // Zero if the drawable can't describe itself
+}
+
+const (
+	termPanelWidth  = 40
+	termLegendLines = 3
+	termDefaultCols = 120
+	termDefaultRows = 40
+)
+
+func NewTerminal() *Terminal {
+	return &Terminal{
+		out: os.Stdout,
+		fd:  os.Stdout.Fd(),
+	}
+}
+
+func (t *Terminal) Init(w, h float64) error {
+	t.width = w
+	t.height = h
+	return nil
+}
+
+func (t *Terminal) Resize(w, h float64) error {
+	return t.Init(w, h)
+}
+
+func (t *Terminal) StartFrame() error {
+	t.glyphs = nil
+	t.panel = nil
+	return nil
+}
+
+func (t *Terminal) Draw(d Drawable) error {
+	i, j, b := d.Screen()
+	g := termGlyph{b: b, pos: pos.Pos{X: float64(i), Y: float64(j)}}
+	if desc, ok := d.(Describer); ok {
+		g.d = desc.Describe()
+		g.pos = g.d.Pos
+		if g.d.Kind == "creech" {
+			t.panel = append(t.panel, g.d)
+		}
+	}
+	t.glyphs = append(t.glyphs, g)
+	return nil
+}
+
+// Terminal size in characters, from the tty, the environment or a default
+func (t *Terminal) size() (int, int) {
+	cols, rows, err := terminalSize(t.fd)
+	if err == nil && cols > 0 && rows > 0 {
+		return cols, rows
+	}
+	cols, err = strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || cols <= 0 {
+		cols = termDefaultCols
+	}
+	rows, err = strconv.Atoi(os.Getenv("LINES"))
+	if err != nil || rows <= 0 {
+		rows = termDefaultRows
+	}
+	return cols, rows
+}
+
+func (t *Terminal) FinishFrame() error {
+	cols, rows := t.size()
+	// Room for the border, gap and panel to the side, legend below
+	mapCols := cols - termPanelWidth - 3
+	mapRows := rows - termLegendLines - 2
+	if mapCols < 1 || mapRows < 1 {
+		return fmt.Errorf("terminal too small: %dx%d", cols, rows)
+	}
+
+	// Never scale up, a cell is at least a metre
+	metresPerCell := math.Max(1, math.Max(t.width/float64(mapCols), t.height/float64(mapRows)))
+	gridW := int(math.Ceil(t.width / metresPerCell))
+	gridH := int(math.Ceil(t.height / metresPerCell))
+
+	cells := make([][][]termGlyph, gridH)
+	for j := range cells {
+		cells[j] = make([][]termGlyph, gridW)
+	}
+	for _, g := range t.glyphs {
+		i := wrapIndex(int(math.Floor((g.pos.X+t.width/2)/metresPerCell)), gridW)
+		j := wrapIndex(int(math.Floor((t.height/2-g.pos.Y)/metresPerCell)), gridH)
+		cells[j][i] = append(cells[j][i], g)
+	}
+
+	var lines []string
+	border := "+" + strings.Repeat("-", gridW) + "+"
+	lines = append(lines, border)
+	for j := range cells {
+		var sb strings.Builder
+		sb.WriteString("|")
+		for i := range cells[j] {
+			sb.WriteString(termCell(cells[j][i]))
+		}
+		sb.WriteString("|")
+		lines = append(lines, sb.String())
+	}
+	lines = append(lines, border)
+
+	panel := t.panelLines(len(lines))
+	w := bufio.NewWriter(t.out)
+	// Home, and overwrite in place, to avoid flicker
+	fmt.Fprint(w, "\x1b[H")
+	for j, line := range lines {
+		fmt.Fprintf(w, "%s %s\x1b[K\n", line, panel[j])
+	}
+	for _, line := range t.legend(metresPerCell) {
+		fmt.Fprintf(w, "%s\x1b[K\n", line)
+	}
+	// Clear anything left below
+	fmt.Fprint(w, "\x1b[J")
+	return w.Flush()
+}
+
+func sgr(codes ...int) string {
+	s := make([]string, len(codes))
+	for i, c := range codes {
+		s[i] = strconv.Itoa(c)
+	}
+	return "\x1b[" + strings.Join(s, ";") + "m"
+}
+
+const (
+	sgrReset  = 0
+	sgrBold   = 1
+	fgGreen   = 32
+	fgGrey    = 90
+	bgRed     = 41
+	bgYellow  = 43
+	bgDefault = 49
+)
+
+// Avoid red and yellow, they are used to show hunger
+var termSpeciesColours = []int{36, 35, 34, 37, 96, 95, 94}
+
+func speciesColour(species string) int {
+	h := fnv.New32a()
+	h.Write([]byte(species))
+	return termSpeciesColours[h.Sum32()%uint32(len(termSpeciesColours))]
+}
+
+func hungerBackground(hunger float64) int {
+	switch {
+	case hunger > 0.85:
+		return bgRed
+	case hunger > 0.6:
+		return bgYellow
+	default:
+		return bgDefault
+	}
+}
+
+// One cell of the map. Live creeches beat dead ones, which beat food.
+// Several live creeches in one cell are shown as their count.
+func termCell(gs []termGlyph) string {
+	if len(gs) == 0 {
+		return " "
+	}
+
+	var live []termGlyph
+	best := gs[0]
+	for _, g := range gs {
+		if g.d.Kind == "creech" && !g.d.Dead {
+			live = append(live, g)
+		}
+		if termPriority(g) > termPriority(best) {
+			best = g
+		}
+	}
+
+	b := best.b
+	if len(live) > 1 {
+		b = '+'
+		if len(live) <= 9 {
+			b = byte('0' + len(live))
+		}
+	}
+
+	switch {
+	case best.d.Kind == "creech" && best.d.Dead:
+		return sgr(fgGrey) + string(b) + sgr(sgrReset)
+	case best.d.Kind == "creech":
+		return sgr(sgrBold, speciesColour(best.d.Species), hungerBackground(best.d.Hunger)) + string(b) + sgr(sgrReset)
+	case best.d.Kind == "food":
+		return sgr(fgGreen) + string(b) + sgr(sgrReset)
+	default:
+		return string(b)
+	}
+}
+
+func termPriority(g termGlyph) int {
+	switch {
+	case g.d.Kind == "creech" && !g.d.Dead:
+		return 3
+	case g.d.Kind == "creech":
+		return 2
+	case g.d.Kind == "food":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Exactly n lines, padded or truncated
+func (t *Terminal) panelLines(n int) []string {
+	lines := []string{fmt.Sprintf("%-10s %-8s %5s %s", "NAME", "SPECIES", "FOOD", "PLAN")}
+	for i, d := range t.panel {
+		if len(lines) == n-1 && i < len(t.panel)-1 {
+			lines = append(lines, fmt.Sprintf("... %d more", len(t.panel)-i))
+			break
+		}
+		colour := speciesColour(d.Species)
+		if d.Dead {
+			colour = fgGrey
+		}
+		plan := d.Plan
+		if d.Dead {
+			plan = "DEAD"
+		}
+		line := fmt.Sprintf("%-10.10s %-8.8s %5.2f %-.12s", d.Name, d.Species, d.Food, plan)
+		lines = append(lines, sgr(colour)+line+sgr(sgrReset))
+	}
+	for len(lines) < n {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (t *Terminal) legend(metresPerCell float64) []string {
+	return []string{
+		"creech: > ^ < v by facing, colour by species, X dead, 2-9 or + for several in one cell",
+		"food: . o O * by value      hunger: " + sgr(bgYellow) + "hungry" + sgr(sgrReset) + " " + sgr(bgRed) + "starving" + sgr(sgrReset),
+		fmt.Sprintf("scale: %.2f metres per cell, world %gx%g", metresPerCell, t.width, t.height),
+	}
+}
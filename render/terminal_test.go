@@ -0,0 +1,29 @@
+package render
+
+import "testing"
+
+func TestTermCell(t *testing.T) {
+	creech := termGlyph{b: '>', d: Description{Kind: "creech", Species: "a"}}
+	dead := termGlyph{b: 'X', d: Description{Kind: "creech", Species: "a", Dead: true}}
+	food := termGlyph{b: 'o', d: Description{Kind: "food"}}
+	plain := termGlyph{b: '?'}
+
+	testCases := []struct {
+		gs       []termGlyph
+		expected string
+	}{
+		{nil, " "},
+		{[]termGlyph{plain}, "?"},
+		{[]termGlyph{food}, sgr(fgGreen) + "o" + sgr(sgrReset)},
+		{[]termGlyph{food, dead}, sgr(fgGrey) + "X" + sgr(sgrReset)},
+		{[]termGlyph{dead, creech, food}, sgr(sgrBold, speciesColour("a"), bgDefault) + ">" + sgr(sgrReset)},
+		{[]termGlyph{creech, food, creech}, sgr(sgrBold, speciesColour("a"), bgDefault) + "2" + sgr(sgrReset)},
+	}
+
+	for _, tc := range testCases {
+		got := termCell(tc.gs)
+		if got != tc.expected {
+			t.Fatalf("got %q expected %q", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package render
+
+import "errors"
+
+func terminalSize(fd uintptr) (int, int, error) {
+	return 0, 0, errors.New("terminal size not supported")
+}
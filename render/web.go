@@ -1,25 +1,96 @@
 package render
 
 import (
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/jbert/creech/pos"
+	"github.com/jbert/creech/prom"
 )
 
 type Web struct {
 	hostport       string
 	mux            *http.ServeMux
+	srv            *http.Server // Set by Init
+	addr           net.Addr     // Listening on, set by Init
 	width          float64
 	height         float64
-	pixelsPerMetre float64
+	pixelsPerMetre float64 // Maximum initial zoom
+	canvasPixels   int
 
 	rootTemplate *template.Template
-	drawCh       chan DrawCommand
+	cmdCh        chan Command
+	log          *slog.Logger
+
+	// The frame being drawn, sent to all clients on FinishFrame
+	frame    []DrawCommand
+	overview DrawCommand
+
+	mu      sync.Mutex
+	clients map[*webClient]bool
+
+	dropped uint64 // Frames not sent to slow clients, atomic
+}
+
+// Wire formats for draw commands, chosen per connection. JSON unless
+// the client asks for ?format=binary, which the page passes on to /ws.
+const (
+	formatJSON   = "json"
+	formatBinary = "binary"
+)
+
+type webClient struct {
+	conn   *websocket.Conn
+	format string
+	sendCh chan []DrawCommand
+	doneCh chan struct{}
+
+	mu sync.Mutex
+	// World co-ordinates the client can see, nothing is culled if unset
+	viewMin, viewMax pos.Pos
+	hasView          bool
+}
+
+func (c *webClient) setViewport(min, max pos.Pos) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Keep things just off screen, so panning doesn't show gaps
+	margin := max.Sub(min).Scale(0.25)
+	c.viewMin = min.Sub(margin)
+	c.viewMax = max.Add(margin)
+	c.hasView = true
+}
+
+// Drop anything entirely outside the client's viewport
+func (c *webClient) cull(cmds []DrawCommand) []DrawCommand {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.hasView {
+		return cmds
+	}
+	visible := make([]DrawCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		min, max, ok := cmd.Bounds()
+		if ok && cmd.What == DrawPoly {
+			if max.X < c.viewMin.X || min.X > c.viewMax.X ||
+				max.Y < c.viewMin.Y || min.Y > c.viewMax.Y {
+				continue
+			}
+		}
+		visible = append(visible, cmd)
+	}
+	return visible
 }
 
 //go:embed static/root.html
@@ -31,97 +102,321 @@ func NewWeb(hostport string) *Web {
 		mux:      http.NewServeMux(),
 
 		pixelsPerMetre: 20.0,
+		canvasPixels:   800,
 
 		rootTemplate: template.Must(template.New("root").Parse(rootTemplateString)),
-		drawCh:       make(chan DrawCommand),
+		cmdCh:        make(chan Command, 16),
+		clients:      make(map[*webClient]bool),
+		log:          slog.Default().With("renderer", "web"),
 	}
 }
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// permessage-deflate, if the browser offers it
+	EnableCompression: true,
 }
 
 func (w *Web) handleWebSocket(rw http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+	if format != formatJSON && format != formatBinary {
+		http.Error(rw, fmt.Sprintf("Unknown format: %s", format), http.StatusBadRequest)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(rw, r, nil)
 	if err != nil {
 		http.Error(rw, fmt.Sprintf("Can't upgrade websocket: %s", err), http.StatusInternalServerError)
 		return
 	}
-	for cmd := range w.drawCh {
-		err = conn.WriteJSON(cmd)
+	defer conn.Close()
+	conn.EnableWriteCompression(true)
+
+	c := &webClient{
+		conn:   conn,
+		format: format,
+		// Small buffer, a slow client drops frames rather than blocking the game
+		sendCh: make(chan []DrawCommand, 4),
+		doneCh: make(chan struct{}),
+	}
+	w.addClient(c)
+	defer w.removeClient(c)
+	w.log.Info("Client connected", "remote", r.RemoteAddr, "format", format)
+
+	go w.readCommands(c)
+	err = c.writeLoop()
+	if err != nil {
+		w.log.Warn("Can't write to websocket", "remote", r.RemoteAddr, "err", err)
+	}
+	w.log.Info("Client disconnected", "remote", r.RemoteAddr)
+}
+
+func (w *Web) addClient(c *webClient) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clients[c] = true
+}
+
+func (w *Web) removeClient(c *webClient) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.clients, c)
+}
+
+// Send to all clients, dropping for any which are not keeping up
+func (w *Web) broadcast(cmds []DrawCommand) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for c := range w.clients {
+		select {
+		case c.sendCh <- cmds:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+		}
+	}
+}
+
+func (c *webClient) writeLoop() error {
+	var enc binaryEncoder
+	for {
+		var cmds []DrawCommand
+		select {
+		case cmds = <-c.sendCh:
+		case <-c.doneCh:
+			return nil
+		}
+		cmds = c.cull(cmds)
+
+		if c.format == formatJSON || cmds[0].What == ShowInfo {
+			for _, cmd := range cmds {
+				err := c.conn.WriteJSON(cmd)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		err := c.conn.WriteMessage(websocket.BinaryMessage, enc.Encode(cmds))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (w *Web) readCommands(c *webClient) {
+	defer close(c.doneCh)
+	for {
+		var cmd Command
+		err := c.conn.ReadJSON(&cmd)
 		if err != nil {
-			log.Printf("Can't writeJSON to websocket: %s", err)
+			w.log.Debug("Stopped reading websocket", "err", err)
 			return
 		}
+		if cmd.What == CmdSetViewport {
+			c.setViewport(cmd.Pos, cmd.To)
+			continue
+		}
+		w.cmdCh <- cmd
 	}
 }
 
+// Handle serves extra endpoints alongside the game, call before Init
+func (w *Web) Handle(pattern string, h http.Handler) {
+	w.mux.Handle(pattern, h)
+}
+
+// RegisterStats exposes client counts and dropped frames
+func (w *Web) RegisterStats(reg *prom.Registry) {
+	reg.NewGaugeFunc("creech_web_clients", "Connected websocket clients.", func() float64 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return float64(len(w.clients))
+	})
+	reg.NewCounterFunc("creech_web_frames_dropped_total", "Frames dropped for clients not keeping up.", func() float64 {
+		return float64(atomic.LoadUint64(&w.dropped))
+	})
+}
+
+func (w *Web) Commands() <-chan Command {
+	return w.cmdCh
+}
+
 func (w *Web) handleRoot(rw http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
 	tmplData := struct {
-		WidthPixels  int
-		HeightPixels int
-		Scale        float64
-		WSURL        string
-		StartFrame   int
-		FinishFrame  int
-		DrawPoly     int
+		CanvasPixels   int
+		WorldWidth     float64
+		WorldHeight    float64
+		MaxScale       float64
+		WSURL          string
+		StartFrame     int
+		FinishFrame    int
+		DrawPoly       int
+		ShowInfo       int
+		DrawOverview   int
+		CmdPause       int
+		CmdResume      int
+		CmdStep        int
+		CmdSetTick     int
+		CmdInspect     int
+		CmdAddFood     int
+		CmdAddCreech   int
+		CmdMove        int
+		CmdDelete      int
+		CmdSetViewport int
+		CmdSeek        int
 	}{
-		int(w.pixelsPerMetre * w.width),
-		int(w.pixelsPerMetre * w.height),
+		w.canvasPixels,
+		w.width,
+		w.height,
 		w.pixelsPerMetre,
-		"ws",
+		"ws?format=" + format,
 		int(StartFrame),
 		int(FinishFrame),
 		int(DrawPoly),
+		int(ShowInfo),
+		int(DrawOverview),
+		int(CmdPause),
+		int(CmdResume),
+		int(CmdStep),
+		int(CmdSetTick),
+		int(CmdInspect),
+		int(CmdAddFood),
+		int(CmdAddCreech),
+		int(CmdMove),
+		int(CmdDelete),
+		int(CmdSetViewport),
+		int(CmdSeek),
 	}
 	err := w.rootTemplate.Execute(rw, tmplData)
 	if err != nil {
 		http.Error(rw, fmt.Sprintf("Can't render template: %s", err), http.StatusInternalServerError)
 		return
 	}
-	w.rootTemplate.Execute(os.Stdout, tmplData)
 }
 
 func (w *Web) Init(width, height float64) error {
 	w.width = width
 	w.height = height
 
+	// Listen here, so a port in use is an error for the caller
+	ln, err := net.Listen("tcp", w.hostport)
+	if err != nil {
+		return fmt.Errorf("Can't listen: %w", err)
+	}
+	w.addr = ln.Addr()
 	w.mux.HandleFunc("/", w.handleRoot)
 	w.mux.HandleFunc("/ws", w.handleWebSocket)
+	w.srv = &http.Server{Handler: w.mux}
+	w.log.Info("Listening", "addr", w.addr)
 	go func() {
-		log.Printf("About to listen on [%s]", w.hostport)
-		err := http.ListenAndServe(w.hostport, w.mux)
-		if err != nil {
-			log.Printf("Failed to ListenAndServe: %s\n", err)
+		err := w.srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			w.log.Error("Can't serve", "err", err)
 		}
 	}()
 	return nil
 }
 
-func (w *Web) StartFrame() error {
-	select {
-	// Just drop it if we have no connection
-	case w.drawCh <- DrawCommand{What: StartFrame}:
+// Addr is where Init is listening, e.g. to find the port for ":0"
+func (w *Web) Addr() net.Addr {
+	return w.addr
+}
+
+// Time allowed for requests to finish and clients to hear we're going
+const webCloseTimeout = time.Second
+
+// Close stops serving and disconnects the clients
+func (w *Web) Close() error {
+	if w.srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webCloseTimeout)
+	defer cancel()
+	err := w.srv.Shutdown(ctx)
+
+	// Shutdown leaves websockets alone, as they are hijacked
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server stopping")
+	w.mu.Lock()
+	clients := make([]*webClient, 0, len(w.clients))
+	for c := range w.clients {
+		clients = append(clients, c)
 	}
+	w.mu.Unlock()
+	// Together, so slow clients take one timeout between them, and
+	// unlocked, so they can still unregister
+	deadline := time.Now().Add(webCloseTimeout)
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *webClient) {
+			defer wg.Done()
+			c.conn.WriteControl(websocket.CloseMessage, msg, deadline)
+			c.conn.Close()
+		}(c)
+	}
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("Can't shut down: %w", err)
+	}
+	return nil
+}
+
+func (w *Web) StartFrame() error {
+	// Fresh slices, clients may still be sending the last one
+	w.frame = []DrawCommand{{What: StartFrame}}
+	w.overview = DrawCommand{What: DrawOverview}
 	return nil
 }
 
 func (w *Web) FinishFrame() error {
-	select {
-	// Just drop it if we have no connection
-	case w.drawCh <- DrawCommand{What: FinishFrame}:
-	}
+	w.frame = append(w.frame, w.overview, DrawCommand{What: FinishFrame})
+	w.broadcast(w.frame)
+	w.frame = nil
 	return nil
 }
 
 func (w *Web) Draw(d Drawable) error {
 	cmds := d.Web()
-	for _, cmd := range cmds {
-		select {
-		// Just drop it if we have no connection
-		case w.drawCh <- cmd:
+	// Clients match entities between frames to move them smoothly
+	if e, ok := d.(interface{ ID() int64 }); ok {
+		for i := range cmds {
+			cmds[i].ID = e.ID()
 		}
 	}
+	if len(cmds) > 0 {
+		min, max, ok := cmds[0].Bounds()
+		if ok {
+			w.overview.Points = append(w.overview.Points, min.Add(max).Scale(0.5))
+		}
+	}
+	for _, cmd := range cmds {
+		w.frame = append(w.frame, WrapCopies(cmd, w.width, w.height)...)
+	}
+	return nil
+}
+
+// DrawBackground adds to the frame without marking the minimap, call
+// before drawing anything else
+func (w *Web) DrawBackground(cmds []DrawCommand) error {
+	for _, cmd := range cmds {
+		w.frame = append(w.frame, WrapCopies(cmd, w.width, w.height)...)
+	}
+	return nil
+}
+
+func (w *Web) Inspect(i Inspection) error {
+	cmd := DrawCommand{What: ShowInfo, Info: i.Info}
+	if i.Selected != nil {
+		cmd.Points = []pos.Pos{*i.Selected}
+	}
+	w.broadcast([]DrawCommand{cmd})
 	return nil
 }
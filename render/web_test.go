@@ -0,0 +1,128 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jbert/creech/pos"
+)
+
+func TestClientCull(t *testing.T) {
+	near := Poly([]pos.Pos{{X: 1, Y: 1}, {X: 2, Y: 2}})
+	far := Poly([]pos.Pos{{X: 100, Y: 100}, {X: 101, Y: 101}})
+	straddle := Poly([]pos.Pos{{X: -20, Y: 0}, {X: 20, Y: 0}})
+	frame := []DrawCommand{{What: StartFrame}, near, far, straddle, {What: FinishFrame}}
+
+	var c webClient
+	got := c.cull(frame)
+	if len(got) != len(frame) {
+		t.Fatalf("expected no culling without viewport, got %d commands", len(got))
+	}
+
+	c.setViewport(pos.Pos{X: -4, Y: -4}, pos.Pos{X: 4, Y: 4})
+	got = c.cull(frame)
+	expected := []DrawCommand{frame[0], near, straddle, frame[4]}
+	if len(got) != len(expected) {
+		t.Fatalf("got %d commands, expected %d", len(got), len(expected))
+	}
+	for i := range got {
+		gotMin, _, _ := got[i].Bounds()
+		expectedMin, _, _ := expected[i].Bounds()
+		if got[i].What != expected[i].What || gotMin != expectedMin {
+			t.Fatalf("command %d: got %+v expected %+v", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestWebListenAndClose(t *testing.T) {
+	w := NewWeb("127.0.0.1:0")
+	err := w.Init(10, 10)
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	addr := w.Addr().String()
+
+	// The port is taken
+	err = NewWeb(addr).Init(10, 10)
+	if err == nil {
+		t.Fatalf("expected an error listening on %s twice", addr)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial: %s", err)
+	}
+	defer conn.Close()
+	waitForClients(w, 1)
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("got %v expected the server going away", err)
+	}
+	_, _, err = websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	if err == nil {
+		t.Fatalf("expected no new connections after Close")
+	}
+}
+
+func waitForClients(w *Web, n int) {
+	for i := 0; i < 100; i++ {
+		w.mu.Lock()
+		got := len(w.clients)
+		w.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebFormat(t *testing.T) {
+	w := NewWeb("127.0.0.1:0")
+	err := w.Init(10, 10)
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	defer w.Close()
+	addr := w.Addr().String()
+
+	testCases := []struct {
+		query    string
+		expected int
+	}{
+		{"", websocket.TextMessage},
+		{"?format=json", websocket.TextMessage},
+		{"?format=binary", websocket.BinaryMessage},
+	}
+	var conns []*websocket.Conn
+	for _, tc := range testCases {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws"+tc.query, nil)
+		if err != nil {
+			t.Fatalf("Dial %s: %s", tc.query, err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	waitForClients(w, len(testCases))
+
+	w.StartFrame()
+	w.FinishFrame()
+	for i, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		conns[i].SetReadDeadline(time.Now().Add(time.Second))
+		got, _, err := conns[i].ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %s", err)
+		}
+		if got != tc.expected {
+			t.Fatalf("Got %v expected %v", got, tc.expected)
+		}
+	}
+}
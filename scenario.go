@@ -0,0 +1,334 @@
+package creech
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// Scenario is a config plus events scheduled by tick, and conditions
+// which end the run and say whether it went as expected
+type Scenario struct {
+	Seed     int64 // For headless runs, so they are repeatable
+	Config   Config
+	Events   []Event
+	MaxTicks int         // The run ends after this many ticks, 0 for no limit
+	End      []Condition // The run ends when any of these hold
+	Expect   []Condition // All of these should hold when the run ends
+}
+
+// Event kinds
+const (
+	EventFamine   = "famine"   // Remove a fraction of the food
+	EventFood     = "food"     // Change the food ecology
+	EventSpawn    = "spawn"    // Add a population
+	EventResize   = "resize"   // Change the world size
+	EventObstacle = "obstacle" // Place an obstacle
+)
+
+type Event struct {
+	Tick int
+	Do   string
+
+	Fraction   float64           // EventFamine
+	Food       *FoodConfig       // EventFood
+	Population *PopulationConfig // EventSpawn
+	Width      float64           // EventResize
+	Height     float64           // EventResize
+	Obstacle   *ObstacleConfig   // EventObstacle
+}
+
+// Condition subjects
+const (
+	CondTick       = "tick"
+	CondPopulation = "population" // Live creeches, of Species if set
+	CondFood       = "food"       // Total value lying in the world
+)
+
+// Condition compares a measure of the game with a value, e.g.
+// {"What": "population", "Op": ">=", "Value": 5}
+type Condition struct {
+	What    string
+	Species string // CondPopulation
+	Op      string
+	Value   float64
+	After   int // An End condition is only checked from this tick on
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	sc := Scenario{Config: DefaultConfig()}
+	err := decodeFile(path, &sc)
+	if err != nil {
+		return nil, err
+	}
+	err = sc.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &sc, nil
+}
+
+func (sc *Scenario) Validate() error {
+	err := sc.Config.Validate()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if sc.MaxTicks < 0 {
+		return fmt.Errorf("MaxTicks must not be negative, got %d", sc.MaxTicks)
+	}
+	terrain := NewTerrain(sc.Config.Obstacles, nil)
+	for i, ev := range sc.Events {
+		err = ev.validate(sc.Config.WorldSize(), terrain)
+		if err != nil {
+			return fmt.Errorf("events[%d]: %w", i, err)
+		}
+	}
+	for i, cond := range sc.End {
+		err = cond.validate()
+		if err != nil {
+			return fmt.Errorf("end[%d]: %w", i, err)
+		}
+	}
+	for i, cond := range sc.Expect {
+		err = cond.validate()
+		if err != nil {
+			return fmt.Errorf("expect[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (ev *Event) validate(worldSize Pos, terrain *Terrain) error {
+	if ev.Tick < 1 {
+		return fmt.Errorf("tick must be at least 1, got %d", ev.Tick)
+	}
+	switch ev.Do {
+	case EventFamine:
+		if !(ev.Fraction > 0 && ev.Fraction <= 1) {
+			return fmt.Errorf("famine fraction must be in (0, 1], got %g", ev.Fraction)
+		}
+	case EventFood:
+		if ev.Food == nil {
+			return errors.New("food event needs Food")
+		}
+		return ev.Food.validate()
+	case EventSpawn:
+		if ev.Population == nil {
+			return errors.New("spawn event needs Population")
+		}
+		return ev.Population.validate(worldSize, terrain)
+	case EventResize:
+		if !(ev.Width > 0) || !(ev.Height > 0) {
+			return fmt.Errorf("size must be positive, got %gx%g", ev.Width, ev.Height)
+		}
+	case EventObstacle:
+		if ev.Obstacle == nil {
+			return errors.New("obstacle event needs Obstacle")
+		}
+		return validatePolygon(ev.Obstacle.Points)
+	default:
+		return fmt.Errorf("unknown event %q", ev.Do)
+	}
+	return nil
+}
+
+func (cond *Condition) validate() error {
+	switch cond.What {
+	case CondTick, CondPopulation, CondFood:
+	default:
+		return fmt.Errorf("unknown condition %q", cond.What)
+	}
+	switch cond.Op {
+	case "<", "<=", "==", ">=", ">":
+	default:
+		return fmt.Errorf("unknown comparison %q", cond.Op)
+	}
+	return nil
+}
+
+func (cond Condition) String() string {
+	what := cond.What
+	if cond.Species != "" {
+		what += "(" + cond.Species + ")"
+	}
+	return fmt.Sprintf("%s %s %g", what, cond.Op, cond.Value)
+}
+
+func (g *Game) measure(cond Condition) float64 {
+	switch cond.What {
+	case CondTick:
+		return float64(g.ticks)
+	case CondPopulation:
+		live := Filter(&g.state.entities, func(c *Creech) bool {
+			return !c.Dead() && (cond.Species == "" || c.params.Species == cond.Species)
+		})
+		return float64(len(live))
+	case CondFood:
+		total := 0.0
+		for _, f := range g.state.food() {
+			total += f.value
+		}
+		return total
+	default:
+		panic(fmt.Sprintf("wtf: %s", cond.What))
+	}
+}
+
+func (g *Game) holds(cond Condition) bool {
+	v := g.measure(cond)
+	switch cond.Op {
+	case "<":
+		return v < cond.Value
+	case "<=":
+		return v <= cond.Value
+	case "==":
+		return v == cond.Value
+	case ">=":
+		return v >= cond.Value
+	case ">":
+		return v > cond.Value
+	default:
+		panic(fmt.Sprintf("wtf: %s", cond.Op))
+	}
+}
+
+// SetScenario uses the scenario's config and schedules its events,
+// call before Init. The scenario itself is left as it is.
+func (g *Game) SetScenario(sc *Scenario) error {
+	err := g.SetConfig(sc.Config)
+	if err != nil {
+		return err
+	}
+	// Events fire in tick order, keeping file order within a tick
+	scheduled := *sc
+	scheduled.Events = append([]Event(nil), sc.Events...)
+	sort.SliceStable(scheduled.Events, func(i, j int) bool {
+		return scheduled.Events[i].Tick < scheduled.Events[j].Tick
+	})
+	g.scenario = &scheduled
+	g.nextEvent = 0
+	return nil
+}
+
+// Whether any event changes the world size, which the renderer must
+// then be able to follow
+func (sc *Scenario) resizes() bool {
+	for _, ev := range sc.Events {
+		if ev.Do == EventResize {
+			return true
+		}
+	}
+	return false
+}
+
+// Fire the events due by now
+func (g *Game) runEvents() {
+	if g.scenario == nil {
+		return
+	}
+	events := g.scenario.Events
+	for g.nextEvent < len(events) && events[g.nextEvent].Tick <= g.ticks {
+		g.fire(events[g.nextEvent])
+		g.nextEvent++
+	}
+}
+
+func (g *Game) fire(ev Event) {
+	switch ev.Do {
+	case EventFamine:
+		food := g.state.food()
+		n := int(ev.Fraction*float64(len(food)) + 0.5)
+		for _, i := range g.state.rand.Perm(len(food))[:n] {
+			g.state.entities.Remove(food[i].ID())
+		}
+	case EventFood:
+		g.config.Food = *ev.Food
+	case EventSpawn:
+		err := g.state.AddCreeches([]PopulationConfig{*ev.Population}, g.worldSize)
+		if err != nil {
+			// A crowded world may not fit them all, keep those we placed
+			g.log.Warn("Can't spawn", "tick", g.ticks, "err", err)
+		}
+	case EventResize:
+		// The renderer is told before it next draws
+		g.config.World.Width = ev.Width
+		g.config.World.Height = ev.Height
+		g.worldSize = g.config.WorldSize()
+		for _, e := range g.state.entities.Entities() {
+			err := g.state.moveEntity(e, g.wrap(e.Pos()))
+			if err != nil {
+				g.log.Warn("Can't keep in the world", "tick", g.ticks, "id", e.ID(), "err", err)
+			}
+		}
+	case EventObstacle:
+		// Anything caught inside can still move out
+		obstacles := g.config.Obstacles
+		// Full slice expression, so the scenario's own config isn't changed
+		g.config.Obstacles = append(obstacles[:len(obstacles):len(obstacles)], *ev.Obstacle)
+		// A new Terrain, as a frame being drawn may still hold the old one
+		g.state.terrain = g.state.terrain.withObstacle(*ev.Obstacle)
+	default:
+		panic(fmt.Sprintf("wtf: %s", ev.Do))
+	}
+}
+
+// Whether the scenario says the run is over
+func (g *Game) scenarioEnded() bool {
+	if g.scenario == nil {
+		return false
+	}
+	if g.scenario.MaxTicks > 0 && g.ticks >= g.scenario.MaxTicks {
+		return true
+	}
+	for _, cond := range g.scenario.End {
+		if g.ticks >= cond.After && g.holds(cond) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckExpectations reports each expected condition which doesn't hold
+func (g *Game) CheckExpectations() error {
+	if g.scenario == nil {
+		return nil
+	}
+	var failed []string
+	for _, cond := range g.scenario.Expect {
+		if !g.holds(cond) {
+			failed = append(failed, fmt.Sprintf("%s (was %g)", cond, g.measure(cond)))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("at tick %d expected %s", g.ticks, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// RunScenario simulates without drawing until the scenario ends, and
+// checks its expectations
+func RunScenario(sc *Scenario) (*Game, error) {
+	if sc.MaxTicks == 0 && len(sc.End) == 0 {
+		return nil, errors.New("scenario never ends, set MaxTicks or End")
+	}
+	g := NewGame(render.NewNull(), time.Millisecond, sc.Seed)
+	err := g.SetScenario(sc)
+	if err != nil {
+		return nil, err
+	}
+	err = g.Init()
+	if err != nil {
+		return nil, err
+	}
+	for !g.scenarioEnded() {
+		g.advance()
+	}
+	return g, g.CheckExpectations()
+}
@@ -0,0 +1,179 @@
+package creech
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// Each scenario in the repo is a regression test of emergent behaviour
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob("scenarios/*.json")
+	if err != nil {
+		t.Fatalf("Glob: %s", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("No scenarios")
+	}
+	for _, path := range paths {
+		sc, err := LoadScenario(path)
+		if err != nil {
+			t.Fatalf("LoadScenario: %s", err)
+		}
+		g, err := RunScenario(sc)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		t.Logf("%s: ended at tick %d", path, g.ticks)
+	}
+}
+
+func TestScenarioEvents(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Food.Count = 8
+	cfg.Food.MaxValue = 2
+	sc := &Scenario{
+		Seed:   7,
+		Config: cfg,
+		Events: []Event{
+			{Tick: 1, Do: EventFamine, Fraction: 0.5},
+			{Tick: 2, Do: EventSpawn, Population: &PopulationConfig{Name: "wolf", Species: "wolf", Count: 3}},
+			{Tick: 3, Do: EventResize, Width: 10, Height: 10},
+		},
+		MaxTicks: 3,
+	}
+	err := sc.Validate()
+	if err != nil {
+		t.Fatalf("Validate: %s", err)
+	}
+	g, err := RunScenario(sc)
+	if err != nil {
+		t.Fatalf("RunScenario: %s", err)
+	}
+	if len(g.state.food()) != 4 {
+		t.Fatalf("got %d food after famine expected 4", len(g.state.food()))
+	}
+	wolves := g.measure(Condition{What: CondPopulation, Species: "wolf"})
+	if wolves != 3 {
+		t.Fatalf("got %g wolves expected 3", wolves)
+	}
+	for _, c := range g.state.creeches() {
+		if c.pos.X <= -5 || c.pos.X > 5 || c.pos.Y <= -5 || c.pos.Y > 5 {
+			t.Fatalf("%s outside resized world", c)
+		}
+	}
+}
+
+func TestScenarioErrors(t *testing.T) {
+	testCases := []struct {
+		sc       Scenario
+		expected string
+	}{
+		{Scenario{Events: []Event{{Tick: 0, Do: EventFamine, Fraction: 1}}}, "events[0]: tick must be at least 1, got 0"},
+		{Scenario{Events: []Event{{Tick: 1, Do: "plague"}}}, `events[0]: unknown event "plague"`},
+		{Scenario{Events: []Event{{Tick: 1, Do: EventFamine, Fraction: 2}}}, "events[0]: famine fraction must be in (0, 1], got 2"},
+		{Scenario{Events: []Event{{Tick: 1, Do: EventSpawn}}}, "events[0]: spawn event needs Population"},
+		{Scenario{End: []Condition{{What: "mood", Op: "<"}}}, `end[0]: unknown condition "mood"`},
+		{Scenario{Expect: []Condition{{What: CondTick, Op: "~"}}}, `expect[0]: unknown comparison "~"`},
+	}
+	for _, tc := range testCases {
+		tc.sc.Config = DefaultConfig()
+		err := tc.sc.Validate()
+		if err == nil || err.Error() != tc.expected {
+			t.Fatalf("got error %v expected %q", err, tc.expected)
+		}
+	}
+}
+
+// Draws at the size it was started with
+type fixedSizeRenderer struct{}
+
+func (fixedSizeRenderer) Init(w, h float64) error    { return nil }
+func (fixedSizeRenderer) StartFrame() error          { return nil }
+func (fixedSizeRenderer) FinishFrame() error         { return nil }
+func (fixedSizeRenderer) Draw(render.Drawable) error { return nil }
+
+// Records each size it is told of
+type resizeRenderer struct {
+	render.Null
+	sizes []Pos
+}
+
+func (r *resizeRenderer) Resize(w, h float64) error {
+	r.sizes = append(r.sizes, Pos{X: w, Y: h})
+	return nil
+}
+
+func TestScenarioResize(t *testing.T) {
+	sc := &Scenario{
+		Config: DefaultConfig(),
+		Events: []Event{
+			{Tick: 2, Do: EventResize, Width: 10, Height: 10},
+			{Tick: 1, Do: EventFamine, Fraction: 0.5},
+		},
+		MaxTicks: 3,
+	}
+	err := sc.Validate()
+	if err != nil {
+		t.Fatalf("Validate: %s", err)
+	}
+
+	_, err = New(WithRenderer(fixedSizeRenderer{}), WithScenario(sc))
+	if err == nil {
+		t.Fatalf("expected an error resizing a renderer which can't")
+	}
+
+	for _, fps := range []float64{0, 1000} {
+		t.Logf("TC: %v", fps)
+		r := &resizeRenderer{}
+		g, err := New(WithRenderer(r), WithScenario(sc), WithTickDuration(0), WithFrameRate(fps))
+		if err != nil {
+			t.Fatalf("New: %s", err)
+		}
+		err = g.Run(context.Background())
+		if err != nil {
+			t.Fatalf("Run: %s", err)
+		}
+		expected := []Pos{{X: 10, Y: 10}}
+		if len(r.sizes) != 1 || r.sizes[0] != expected[0] {
+			t.Fatalf("Got %v expected %v", r.sizes, expected)
+		}
+	}
+
+	// Scheduling sorts a copy
+	if sc.Events[0].Do != EventResize {
+		t.Fatalf("Got %v expected the scenario's events left in order", sc.Events)
+	}
+}
+
+// Frames are drawn while obstacles arrive, run with -race to check
+// they don't share the terrain being changed
+func TestObstacleEventsWhileDrawing(t *testing.T) {
+	sc := &Scenario{Config: DefaultConfig(), MaxTicks: 200}
+	for i := 1; i <= sc.MaxTicks; i++ {
+		x := float64(i%20) - 10
+		sc.Events = append(sc.Events, Event{Tick: i, Do: EventObstacle, Obstacle: &ObstacleConfig{
+			Points: []Pos{{X: x, Y: 10}, {X: x + 0.5, Y: 10}, {X: x, Y: 10.5}},
+		}})
+	}
+	err := sc.Validate()
+	if err != nil {
+		t.Fatalf("Validate: %s", err)
+	}
+	g, err := New(WithScenario(sc), WithTickDuration(0), WithFrameRate(1000))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	err = g.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if len(g.state.terrain.obstacles) != sc.MaxTicks {
+		t.Fatalf("Got %v expected %v obstacles", len(g.state.terrain.obstacles), sc.MaxTicks)
+	}
+}
@@ -0,0 +1,149 @@
+package creech
+
+import (
+	"fmt"
+	"reflect"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// Snapshot is a copy of the state of the world at one tick. Creeches
+// and food are copied in full. Every other entity, e.g. one added
+// through the API, has an EntitySnapshot of what all entities have.
+type Snapshot struct {
+	Tick     int
+	Creeches []CreechSnapshot
+	Food     []FoodSnapshot
+	Others   []EntitySnapshot `json:",omitempty"`
+}
+
+type CreechSnapshot struct {
+	ID     int64
+	Name   string
+	Pos    Pos
+	Facing Polar
+	Food   float64
+	Plan   string // Last executed
+	Params Params
+
+	ViewScale float64 // 0 in older recordings, meaning 1
+}
+
+type FoodSnapshot struct {
+	ID    int64
+	Pos   Pos
+	Value float64
+}
+
+type EntitySnapshot struct {
+	ID   int64
+	Kind string // Type name, e.g. "rock" for a *rock
+	Pos  Pos
+	Size float64
+}
+
+func (s *State) Snapshot(tick int) Snapshot {
+	creeches, food := s.creeches(), s.food()
+	snap := Snapshot{
+		Tick:     tick,
+		Creeches: make([]CreechSnapshot, len(creeches)),
+		Food:     make([]FoodSnapshot, len(food)),
+	}
+	for i, c := range creeches {
+		snap.Creeches[i] = CreechSnapshot{
+			ID:     c.ID(),
+			Name:   c.name,
+			Pos:    c.Pos(),
+			Facing: c.facing,
+			Food:   c.food,
+			Plan:   c.lastPlan,
+			Params: c.params,
+
+			ViewScale: c.viewScale,
+		}
+	}
+	for i, f := range food {
+		snap.Food[i] = FoodSnapshot{
+			ID:    f.ID(),
+			Pos:   f.Pos(),
+			Value: f.value,
+		}
+	}
+	for _, e := range s.entities.Entities() {
+		switch e.(type) {
+		case *Creech, *Food:
+			continue
+		}
+		snap.Others = append(snap.Others, EntitySnapshot{
+			ID:   e.ID(),
+			Kind: entityKind(e),
+			Pos:  e.Pos(),
+			Size: e.Size(),
+		})
+	}
+	return snap
+}
+
+func entityKind(e Entity) string {
+	t := reflect.TypeOf(e)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Stands in for an entity of a kind a snapshot doesn't copy in full. It
+// takes up room and can be inspected, but isn't drawn or simulated.
+type placeholder struct {
+	BaseEntity
+	kind string
+	size float64
+}
+
+func (p *placeholder) Size() float64 {
+	return p.size
+}
+
+// Rebuild entities for display, they have no plans and can't be simulated
+func stateFromSnapshot(snap Snapshot) (State, error) {
+	var s State
+	for _, cs := range snap.Creeches {
+		viewScale := cs.ViewScale
+		if viewScale == 0 {
+			viewScale = 1
+		}
+		err := s.entities.insert(&Creech{
+			BaseEntity: BaseEntity{id: cs.ID, pos: cs.Pos},
+			params:     cs.Params,
+			name:       cs.Name,
+			facing:     cs.Facing,
+			food:       cs.Food,
+			lastPlan:   cs.Plan,
+			viewScale:  viewScale,
+		})
+		if err != nil {
+			return State{}, fmt.Errorf("Can't rebuild creech %s: %w", cs.Name, err)
+		}
+	}
+	for _, fs := range snap.Food {
+		err := s.entities.insert(&Food{
+			BaseEntity: BaseEntity{id: fs.ID, pos: fs.Pos},
+			value:      fs.Value,
+		})
+		if err != nil {
+			return State{}, fmt.Errorf("Can't rebuild food: %w", err)
+		}
+	}
+	for _, es := range snap.Others {
+		err := s.entities.insert(&placeholder{
+			BaseEntity: BaseEntity{id: es.ID, pos: es.Pos},
+			kind:       es.Kind,
+			size:       es.Size,
+		})
+		if err != nil {
+			return State{}, fmt.Errorf("Can't rebuild %s: %w", es.Kind, err)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,57 @@
+package creech
+
+import "github.com/jbert/creech/prom"
+
+// Live stats for scraping, unlike metrics which are written per tick
+type gameStats struct {
+	ticks        *prom.Counter
+	tickDuration *prom.Histogram
+	tickInterval *prom.Gauge
+	paused       *prom.Gauge
+	entities     *prom.GaugeVec
+}
+
+// Ticks are usually well under a millisecond
+var tickBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// RegisterStats exposes tick timings and entity counts
+func (g *Game) RegisterStats(reg *prom.Registry) {
+	g.stats = &gameStats{
+		ticks:        reg.NewCounter("creech_ticks_total", "Ticks run."),
+		tickDuration: reg.NewHistogram("creech_tick_duration_seconds", "Time spent drawing and updating per tick.", tickBuckets),
+		tickInterval: reg.NewGauge("creech_tick_interval_seconds", "Configured time between ticks."),
+		paused:       reg.NewGauge("creech_paused", "1 if the game is paused."),
+		entities:     reg.NewGaugeVec("creech_entities", "Entities in the world, by kind.", "kind"),
+	}
+	Subscribe(&g.events, func(e StepEvent) {
+		g.stats.ticks.Inc()
+		g.stats.tickDuration.Observe(e.Took.Seconds())
+		g.updateStats()
+	})
+	g.updateStats()
+}
+
+// Gauges are set from the game goroutine, so scrapes never touch State
+func (g *Game) updateStats() {
+	if g.stats == nil {
+		return
+	}
+	g.stats.tickInterval.Set(g.tickDur.Seconds())
+	paused := 0.0
+	if g.paused {
+		paused = 1
+	}
+	g.stats.paused.Set(paused)
+
+	live, dead := 0, 0
+	for _, c := range g.state.creeches() {
+		if c.Dead() {
+			dead++
+		} else {
+			live++
+		}
+	}
+	g.stats.entities.Set("creech", float64(live))
+	g.stats.entities.Set("dead_creech", float64(dead))
+	g.stats.entities.Set("food", float64(Count[*Food](&g.state.entities)))
+}
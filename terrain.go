@@ -0,0 +1,210 @@
+package creech
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// TerrainType changes how creeches move across an area and how well
+// food grows there
+type TerrainType struct {
+	Name       string
+	Speed      float64 // Scales distance moved
+	MoveCost   float64 // Scales energy spent moving
+	FoodGrowth float64 // Scales food regrowth, no food spawns at 0
+}
+
+var openGround = TerrainType{Name: "open", Speed: 1, MoveCost: 1, FoodGrowth: 1}
+
+var terrainTypes = map[string]TerrainType{
+	"grass": {Name: "grass", Speed: 1, MoveCost: 1, FoodGrowth: 2},
+	"rough": {Name: "rough", Speed: 0.5, MoveCost: 2, FoodGrowth: 0.5},
+	"water": {Name: "water", Speed: 0.3, MoveCost: 3, FoodGrowth: 0},
+}
+
+// Obstacles block movement and sight
+type ObstacleConfig struct {
+	Points []Pos
+}
+
+type TerrainConfig struct {
+	Type   string // "grass", "rough" or "water"
+	Points []Pos
+}
+
+func validatePolygon(pts []Pos) error {
+	if len(pts) < 3 {
+		return fmt.Errorf("need at least 3 points, got %d", len(pts))
+	}
+	return nil
+}
+
+func (tc *TerrainConfig) validate() error {
+	_, ok := terrainTypes[tc.Type]
+	if !ok {
+		return fmt.Errorf("unknown terrain type %q", tc.Type)
+	}
+	return validatePolygon(tc.Points)
+}
+
+// Terrain is the static part of the world. The zero value is open
+// ground everywhere. Areas don't wrap around a torus.
+type Terrain struct {
+	obstacles []*Obstacle
+	areas     []*TerrainArea
+}
+
+func NewTerrain(obstacles []ObstacleConfig, areas []TerrainConfig) *Terrain {
+	t := &Terrain{}
+	for _, oc := range obstacles {
+		t.obstacles = append(t.obstacles, &Obstacle{region: NewRegion(oc.Points)})
+	}
+	for _, tc := range areas {
+		t.areas = append(t.areas, &TerrainArea{
+			kind:   terrainTypes[tc.Type],
+			region: NewRegion(tc.Points),
+		})
+	}
+	return t
+}
+
+// At finds the terrain type at p, later areas lie over earlier ones
+func (t *Terrain) At(p Pos) TerrainType {
+	for i := len(t.areas) - 1; i >= 0; i-- {
+		if t.areas[i].region.Contains(p) {
+			return t.areas[i].kind
+		}
+	}
+	return openGround
+}
+
+// A copy of t with another obstacle, t itself is unchanged
+func (t *Terrain) withObstacle(oc ObstacleConfig) *Terrain {
+	obstacles := make([]*Obstacle, len(t.obstacles), len(t.obstacles)+1)
+	copy(obstacles, t.obstacles)
+	return &Terrain{
+		obstacles: append(obstacles, &Obstacle{region: NewRegion(oc.Points)}),
+		areas:     t.areas,
+	}
+}
+
+func (t *Terrain) Blocked(p Pos) bool {
+	for _, o := range t.obstacles {
+		if o.region.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Nearest point where from -> to hits an obstacle, and the wall hit
+func (t *Terrain) firstHit(from, to Pos) (Pos, LineSegment, bool) {
+	ls := NewLineSegment(from, to)
+	var best Pos
+	var bestWall LineSegment
+	found := false
+	for _, o := range t.obstacles {
+		p, wall, ok := o.region.FirstCrossing(ls)
+		if ok && (!found || from.DistanceToSquared(p) < from.DistanceToSquared(best)) {
+			best, bestWall, found = p, wall, true
+		}
+	}
+	return best, bestWall, found
+}
+
+func (t *Terrain) LineOfSight(from, to Pos) bool {
+	_, _, hit := t.firstHit(from, to)
+	return !hit
+}
+
+// Keep clear of walls, so the next move doesn't start on one
+const wallGap = 0.01
+
+// Move goes as far towards to as obstacles allow, sliding along a wall
+// if that gets further
+func (t *Terrain) Move(from, to Pos) Pos {
+	if t.Blocked(from) {
+		// Let anything stuck inside get out
+		return to
+	}
+	hit, wall, ok := t.firstHit(from, to)
+	if !ok {
+		return to
+	}
+
+	stop := from
+	if d := from.DistanceTo(hit); d > wallGap {
+		stop = from.Add(hit.Sub(from).Scale((d - wallGap) / d))
+	}
+
+	// The part of the move along the wall
+	along := wall.To.Sub(wall.From).Unit()
+	move := to.Sub(from)
+	slide := from.Add(along.Scale(move.Dot(along)))
+	_, _, blocked := t.firstHit(from, slide)
+	if !blocked && from.DistanceToSquared(slide) > from.DistanceToSquared(stop) {
+		return slide
+	}
+	return stop
+}
+
+type Obstacle struct {
+	region Region
+}
+
+func (o *Obstacle) Screen() (int, int, byte) {
+	c := o.region.Centroid()
+	return int(math.Floor(c.X)), int(math.Floor(c.Y)), '#'
+}
+
+func (o *Obstacle) Describe() render.Description {
+	return render.Description{
+		Kind: "obstacle",
+		Pos:  o.region.Centroid(),
+	}
+}
+
+func (o *Obstacle) Web() []render.DrawCommand {
+	poly := render.Poly(o.region.ClosedPoints())
+	poly.DoFill = true
+	poly.FillColour = render.RGBA{R: 0.3, G: 0.3, B: 0.3, A: 1}
+	poly.LineColour = poly.FillColour
+	return []render.DrawCommand{poly}
+}
+
+type TerrainArea struct {
+	kind   TerrainType
+	region Region
+}
+
+var terrainColours = map[string]render.RGBA{
+	"grass": {R: 0.4, G: 0.8, B: 0.3, A: 0.3},
+	"rough": {R: 0.6, G: 0.5, B: 0.3, A: 0.3},
+	"water": {R: 0.2, G: 0.4, B: 0.9, A: 0.3},
+}
+
+func (ta *TerrainArea) Screen() (int, int, byte) {
+	c := ta.region.Centroid()
+	b := map[string]byte{"grass": '"', "rough": ':', "water": '~'}[ta.kind.Name]
+	return int(math.Floor(c.X)), int(math.Floor(c.Y)), b
+}
+
+func (ta *TerrainArea) Describe() render.Description {
+	return render.Description{
+		Kind: ta.kind.Name,
+		Pos:  ta.region.Centroid(),
+	}
+}
+
+func (ta *TerrainArea) Web() []render.DrawCommand {
+	poly := render.Poly(ta.region.ClosedPoints())
+	poly.DoFill = true
+	poly.FillColour = terrainColours[ta.kind.Name]
+	poly.LineColour = poly.FillColour
+	return []render.DrawCommand{poly}
+}
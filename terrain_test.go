@@ -0,0 +1,87 @@
+package creech
+
+import (
+	"testing"
+
+	"github.com/jbert/creech/render"
+
+	// pos.Pos shorthand
+	. "github.com/jbert/creech/pos"
+)
+
+// A wall along x=0 from y=-5 to y=5
+var testWall = ObstacleConfig{Points: []Pos{{X: -0.5, Y: -5}, {X: 0.5, Y: -5}, {X: 0.5, Y: 5}, {X: -0.5, Y: 5}}}
+
+func TestTerrainMove(t *testing.T) {
+	terrain := NewTerrain([]ObstacleConfig{testWall}, nil)
+
+	testCases := []struct {
+		from, to Pos
+		expected Pos
+	}{
+		// Clear
+		{Pos{X: -3, Y: 0}, Pos{X: -2, Y: 0}, Pos{X: -2, Y: 0}},
+		{Pos{X: -3, Y: 6}, Pos{X: 3, Y: 6}, Pos{X: 3, Y: 6}},
+		// Head on, stop short of the wall
+		{Pos{X: -3, Y: 0}, Pos{X: 3, Y: 0}, Pos{X: -0.5 - wallGap, Y: 0}},
+		// At an angle, slide along it
+		{Pos{X: -1, Y: 0}, Pos{X: 1, Y: 2}, Pos{X: -1, Y: 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("TC: %v", tc)
+		got := terrain.Move(tc.from, tc.to)
+		if !got.Equals(tc.expected) {
+			t.Fatalf("Got %s expected %s", got, tc.expected)
+		}
+	}
+}
+
+func TestTerrainAt(t *testing.T) {
+	square := []Pos{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	inner := []Pos{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}, {X: 1, Y: 2}}
+	terrain := NewTerrain(nil, []TerrainConfig{{"grass", square}, {"water", inner}})
+
+	testCases := []struct {
+		p        Pos
+		expected string
+	}{
+		{Pos{X: 3, Y: 3}, "grass"},
+		{Pos{X: 1.5, Y: 1.5}, "water"},
+		{Pos{X: 5, Y: 5}, "open"},
+	}
+	for _, tc := range testCases {
+		got := terrain.At(tc.p).Name
+		if got != tc.expected {
+			t.Fatalf("At %s got %s expected %s", tc.p, got, tc.expected)
+		}
+	}
+}
+
+func TestObserveBehindObstacle(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Obstacles = []ObstacleConfig{testWall}
+	cfg.Creeches = []PopulationConfig{
+		{Name: "bob", Count: 1, Pos: []Pos{{X: -3, Y: 0}}},
+		{Name: "alice", Count: 1, Pos: []Pos{{X: 3, Y: 0}}},
+		{Name: "carol", Count: 1, Pos: []Pos{{X: -3, Y: 3}}},
+	}
+	cfg.Food.Count = 0
+	g := NewGame(render.NewNull(), 0, 1)
+	err := g.SetConfig(cfg)
+	if err != nil {
+		t.Fatalf("SetConfig: %s", err)
+	}
+	err = g.Init()
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+
+	bob := g.state.creeches()[0]
+	bob.facing = Polar{R: 1, Theta: 0}
+	view := NewRegion([]Pos{{X: -4, Y: -4}, {X: 4, Y: -4}, {X: 4, Y: 4}, {X: -4, Y: 4}})
+	seen := g.Observe(bob.Pos(), view, bob.ID())
+	if len(seen) != 1 || seen[0] != Entity(g.state.creeches()[2]) {
+		t.Fatalf("Expected bob to see only carol, saw %v", seen)
+	}
+}